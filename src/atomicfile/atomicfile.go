@@ -0,0 +1,94 @@
+package atomicfile
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// A File is a temporary file which replaces the file at its destination path
+// once committed, so the destination is never seen half-written.
+type File struct {
+	*os.File
+	path string
+}
+
+// Create creates a temporary file in the directory of the destination path.
+func Create(path string) (*File, error) {
+	file, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".")
+	if err != nil {
+		return nil, err
+	}
+
+	return &File{
+		File: file,
+		path: path,
+	}, nil
+}
+
+// Commit closes the temporary file and moves it to its destination. The file
+// is flushed to the disk before it is moved, and the move once done, so a
+// crash can't leave a truncated file at the destination.
+func (f *File) Commit() error {
+	err := f.sync()
+	if err == nil {
+		err = f.File.Close()
+		if closed(err) {
+			err = nil
+		}
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	err = os.Chmod(f.Name(), 0644)
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	err = os.Rename(f.Name(), f.path)
+	if err != nil {
+		return err
+	}
+
+	return syncPath(filepath.Dir(f.path))
+}
+
+// sync flushes the temporary file to the disk, reopening it if it was closed
+// to be written by other means.
+func (f *File) sync() error {
+	err := f.File.Sync()
+	if !closed(err) {
+		return err
+	}
+	return syncPath(f.Name())
+}
+
+// syncPath flushes the file or directory at the given path to the disk.
+func syncPath(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	err = file.Sync()
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Abort closes and removes the temporary file, leaving the destination
+// untouched.
+func (f *File) Abort() error {
+	f.File.Close()
+	return os.Remove(f.Name())
+}
+
+func closed(err error) bool {
+	if e, ok := err.(*os.PathError); ok {
+		err = e.Err
+	}
+	return err == os.ErrClosed
+}
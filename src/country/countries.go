@@ -0,0 +1,264 @@
+package country
+
+// Countries is the ISO 3166-1 table, completed with the ISO 3166-2 codes of
+// the United Kingdom nations, which play as separate teams.
+var Countries = []Country{
+	{Code: "AW", Alpha3: "ABW", Name: "Aruba"},
+	{Code: "AF", Alpha3: "AFG", Name: "Afghanistan", Aliases: []string{"Islamic Republic of Afghanistan"}},
+	{Code: "AO", Alpha3: "AGO", Name: "Angola", Aliases: []string{"Republic of Angola"}},
+	{Code: "AI", Alpha3: "AIA", Name: "Anguilla"},
+	{Code: "AX", Alpha3: "ALA", Name: "Åland Islands"},
+	{Code: "AL", Alpha3: "ALB", Name: "Albania", Aliases: []string{"Republic of Albania"}},
+	{Code: "AD", Alpha3: "AND", Name: "Andorra", Aliases: []string{"Principality of Andorra"}},
+	{Code: "AE", Alpha3: "ARE", Name: "United Arab Emirates", Aliases: []string{"UAE"}},
+	{Code: "AR", Alpha3: "ARG", Name: "Argentina", Aliases: []string{"Argentine Republic"}},
+	{Code: "AM", Alpha3: "ARM", Name: "Armenia", Aliases: []string{"Republic of Armenia"}},
+	{Code: "AS", Alpha3: "ASM", Name: "American Samoa"},
+	{Code: "AQ", Alpha3: "ATA", Name: "Antarctica"},
+	{Code: "TF", Alpha3: "ATF", Name: "French Southern Territories"},
+	{Code: "AG", Alpha3: "ATG", Name: "Antigua and Barbuda"},
+	{Code: "AU", Alpha3: "AUS", Name: "Australia"},
+	{Code: "AT", Alpha3: "AUT", Name: "Austria", Aliases: []string{"Republic of Austria"}},
+	{Code: "AZ", Alpha3: "AZE", Name: "Azerbaijan", Aliases: []string{"Republic of Azerbaijan"}},
+	{Code: "BI", Alpha3: "BDI", Name: "Burundi", Aliases: []string{"Republic of Burundi"}},
+	{Code: "BE", Alpha3: "BEL", Name: "Belgium", Aliases: []string{"Kingdom of Belgium"}},
+	{Code: "BJ", Alpha3: "BEN", Name: "Benin", Aliases: []string{"Republic of Benin"}},
+	{Code: "BQ", Alpha3: "BES", Name: "Bonaire, Sint Eustatius and Saba"},
+	{Code: "BF", Alpha3: "BFA", Name: "Burkina Faso"},
+	{Code: "BD", Alpha3: "BGD", Name: "Bangladesh", Aliases: []string{"People's Republic of Bangladesh"}},
+	{Code: "BG", Alpha3: "BGR", Name: "Bulgaria", Aliases: []string{"Republic of Bulgaria"}},
+	{Code: "BH", Alpha3: "BHR", Name: "Bahrain", Aliases: []string{"Kingdom of Bahrain"}},
+	{Code: "BS", Alpha3: "BHS", Name: "Bahamas", Aliases: []string{"Commonwealth of the Bahamas"}},
+	{Code: "BA", Alpha3: "BIH", Name: "Bosnia and Herzegovina", Aliases: []string{"Republic of Bosnia and Herzegovina"}},
+	{Code: "BL", Alpha3: "BLM", Name: "Saint Barthélemy"},
+	{Code: "BY", Alpha3: "BLR", Name: "Belarus", Aliases: []string{"Republic of Belarus"}},
+	{Code: "BZ", Alpha3: "BLZ", Name: "Belize"},
+	{Code: "BM", Alpha3: "BMU", Name: "Bermuda"},
+	{Code: "BO", Alpha3: "BOL", Name: "Bolivia", Aliases: []string{"Bolivia, Plurinational State of", "Plurinational State of Bolivia"}},
+	{Code: "BR", Alpha3: "BRA", Name: "Brazil", Aliases: []string{"Federative Republic of Brazil"}},
+	{Code: "BB", Alpha3: "BRB", Name: "Barbados"},
+	{Code: "BN", Alpha3: "BRN", Name: "Brunei Darussalam"},
+	{Code: "BT", Alpha3: "BTN", Name: "Bhutan", Aliases: []string{"Kingdom of Bhutan"}},
+	{Code: "BV", Alpha3: "BVT", Name: "Bouvet Island"},
+	{Code: "BW", Alpha3: "BWA", Name: "Botswana", Aliases: []string{"Republic of Botswana"}},
+	{Code: "CF", Alpha3: "CAF", Name: "Central African Republic"},
+	{Code: "CA", Alpha3: "CAN", Name: "Canada"},
+	{Code: "CC", Alpha3: "CCK", Name: "Cocos (Keeling) Islands"},
+	{Code: "CH", Alpha3: "CHE", Name: "Switzerland", Aliases: []string{"Swiss Confederation"}},
+	{Code: "CL", Alpha3: "CHL", Name: "Chile", Aliases: []string{"Republic of Chile"}},
+	{Code: "CN", Alpha3: "CHN", Name: "China", Aliases: []string{"People's Republic of China"}},
+	{Code: "CI", Alpha3: "CIV", Name: "Côte d'Ivoire", Aliases: []string{"Republic of Côte d'Ivoire"}},
+	{Code: "CM", Alpha3: "CMR", Name: "Cameroon", Aliases: []string{"Republic of Cameroon"}},
+	{Code: "CD", Alpha3: "COD", Name: "Congo, The Democratic Republic of the"},
+	{Code: "CG", Alpha3: "COG", Name: "Congo", Aliases: []string{"Republic of the Congo"}},
+	{Code: "CK", Alpha3: "COK", Name: "Cook Islands"},
+	{Code: "CO", Alpha3: "COL", Name: "Colombia", Aliases: []string{"Republic of Colombia"}},
+	{Code: "KM", Alpha3: "COM", Name: "Comoros", Aliases: []string{"Union of the Comoros"}},
+	{Code: "CV", Alpha3: "CPV", Name: "Cabo Verde", Aliases: []string{"Republic of Cabo Verde"}},
+	{Code: "CR", Alpha3: "CRI", Name: "Costa Rica", Aliases: []string{"Republic of Costa Rica"}},
+	{Code: "CU", Alpha3: "CUB", Name: "Cuba", Aliases: []string{"Republic of Cuba"}},
+	{Code: "CW", Alpha3: "CUW", Name: "Curaçao"},
+	{Code: "CX", Alpha3: "CXR", Name: "Christmas Island"},
+	{Code: "KY", Alpha3: "CYM", Name: "Cayman Islands"},
+	{Code: "CY", Alpha3: "CYP", Name: "Cyprus", Aliases: []string{"Republic of Cyprus"}},
+	{Code: "CZ", Alpha3: "CZE", Name: "Czechia", Aliases: []string{"Czech Republic"}},
+	{Code: "DE", Alpha3: "DEU", Name: "Germany", Aliases: []string{"Federal Republic of Germany"}},
+	{Code: "DJ", Alpha3: "DJI", Name: "Djibouti", Aliases: []string{"Republic of Djibouti"}},
+	{Code: "DM", Alpha3: "DMA", Name: "Dominica", Aliases: []string{"Commonwealth of Dominica"}},
+	{Code: "DK", Alpha3: "DNK", Name: "Denmark", Aliases: []string{"Kingdom of Denmark"}},
+	{Code: "DO", Alpha3: "DOM", Name: "Dominican Republic"},
+	{Code: "DZ", Alpha3: "DZA", Name: "Algeria", Aliases: []string{"People's Democratic Republic of Algeria"}},
+	{Code: "EC", Alpha3: "ECU", Name: "Ecuador", Aliases: []string{"Republic of Ecuador"}},
+	{Code: "EG", Alpha3: "EGY", Name: "Egypt", Aliases: []string{"Arab Republic of Egypt"}},
+	{Code: "ER", Alpha3: "ERI", Name: "Eritrea", Aliases: []string{"the State of Eritrea"}},
+	{Code: "EH", Alpha3: "ESH", Name: "Western Sahara"},
+	{Code: "ES", Alpha3: "ESP", Name: "Spain", Aliases: []string{"Kingdom of Spain"}},
+	{Code: "EE", Alpha3: "EST", Name: "Estonia", Aliases: []string{"Republic of Estonia"}},
+	{Code: "ET", Alpha3: "ETH", Name: "Ethiopia", Aliases: []string{"Federal Democratic Republic of Ethiopia"}},
+	{Code: "FI", Alpha3: "FIN", Name: "Finland", Aliases: []string{"Republic of Finland"}},
+	{Code: "FJ", Alpha3: "FJI", Name: "Fiji", Aliases: []string{"Republic of Fiji"}},
+	{Code: "FK", Alpha3: "FLK", Name: "Falkland Islands (Malvinas)"},
+	{Code: "FR", Alpha3: "FRA", Name: "France", Aliases: []string{"French Republic"}},
+	{Code: "FO", Alpha3: "FRO", Name: "Faroe Islands"},
+	{Code: "FM", Alpha3: "FSM", Name: "Micronesia, Federated States of", Aliases: []string{"Federated States of Micronesia"}},
+	{Code: "GA", Alpha3: "GAB", Name: "Gabon", Aliases: []string{"Gabonese Republic"}},
+	{Code: "GB", Alpha3: "GBR", Name: "United Kingdom", Aliases: []string{"United Kingdom of Great Britain and Northern Ireland", "UK", "Great Britain"}},
+	{Code: "GE", Alpha3: "GEO", Name: "Georgia"},
+	{Code: "GG", Alpha3: "GGY", Name: "Guernsey"},
+	{Code: "GH", Alpha3: "GHA", Name: "Ghana", Aliases: []string{"Republic of Ghana"}},
+	{Code: "GI", Alpha3: "GIB", Name: "Gibraltar"},
+	{Code: "GN", Alpha3: "GIN", Name: "Guinea", Aliases: []string{"Republic of Guinea"}},
+	{Code: "GP", Alpha3: "GLP", Name: "Guadeloupe"},
+	{Code: "GM", Alpha3: "GMB", Name: "Gambia", Aliases: []string{"Republic of the Gambia"}},
+	{Code: "GW", Alpha3: "GNB", Name: "Guinea-Bissau", Aliases: []string{"Republic of Guinea-Bissau"}},
+	{Code: "GQ", Alpha3: "GNQ", Name: "Equatorial Guinea", Aliases: []string{"Republic of Equatorial Guinea"}},
+	{Code: "GR", Alpha3: "GRC", Name: "Greece", Aliases: []string{"Hellenic Republic"}},
+	{Code: "GD", Alpha3: "GRD", Name: "Grenada"},
+	{Code: "GL", Alpha3: "GRL", Name: "Greenland"},
+	{Code: "GT", Alpha3: "GTM", Name: "Guatemala", Aliases: []string{"Republic of Guatemala"}},
+	{Code: "GF", Alpha3: "GUF", Name: "French Guiana"},
+	{Code: "GU", Alpha3: "GUM", Name: "Guam"},
+	{Code: "GY", Alpha3: "GUY", Name: "Guyana", Aliases: []string{"Republic of Guyana"}},
+	{Code: "HK", Alpha3: "HKG", Name: "Hong Kong", Aliases: []string{"Hong Kong Special Administrative Region of China"}},
+	{Code: "HM", Alpha3: "HMD", Name: "Heard Island and McDonald Islands"},
+	{Code: "HN", Alpha3: "HND", Name: "Honduras", Aliases: []string{"Republic of Honduras"}},
+	{Code: "HR", Alpha3: "HRV", Name: "Croatia", Aliases: []string{"Republic of Croatia"}},
+	{Code: "HT", Alpha3: "HTI", Name: "Haiti", Aliases: []string{"Republic of Haiti"}},
+	{Code: "HU", Alpha3: "HUN", Name: "Hungary"},
+	{Code: "ID", Alpha3: "IDN", Name: "Indonesia", Aliases: []string{"Republic of Indonesia"}},
+	{Code: "IM", Alpha3: "IMN", Name: "Isle of Man"},
+	{Code: "IN", Alpha3: "IND", Name: "India", Aliases: []string{"Republic of India"}},
+	{Code: "IO", Alpha3: "IOT", Name: "British Indian Ocean Territory"},
+	{Code: "IE", Alpha3: "IRL", Name: "Ireland"},
+	{Code: "IR", Alpha3: "IRN", Name: "Iran", Aliases: []string{"Iran, Islamic Republic of", "Islamic Republic of Iran"}},
+	{Code: "IQ", Alpha3: "IRQ", Name: "Iraq", Aliases: []string{"Republic of Iraq"}},
+	{Code: "IS", Alpha3: "ISL", Name: "Iceland", Aliases: []string{"Republic of Iceland"}},
+	{Code: "IL", Alpha3: "ISR", Name: "Israel", Aliases: []string{"State of Israel"}},
+	{Code: "IT", Alpha3: "ITA", Name: "Italy", Aliases: []string{"Italian Republic"}},
+	{Code: "JM", Alpha3: "JAM", Name: "Jamaica"},
+	{Code: "JE", Alpha3: "JEY", Name: "Jersey"},
+	{Code: "JO", Alpha3: "JOR", Name: "Jordan", Aliases: []string{"Hashemite Kingdom of Jordan"}},
+	{Code: "JP", Alpha3: "JPN", Name: "Japan"},
+	{Code: "KZ", Alpha3: "KAZ", Name: "Kazakhstan", Aliases: []string{"Republic of Kazakhstan"}},
+	{Code: "KE", Alpha3: "KEN", Name: "Kenya", Aliases: []string{"Republic of Kenya"}},
+	{Code: "KG", Alpha3: "KGZ", Name: "Kyrgyzstan", Aliases: []string{"Kyrgyz Republic"}},
+	{Code: "KH", Alpha3: "KHM", Name: "Cambodia", Aliases: []string{"Kingdom of Cambodia"}},
+	{Code: "KI", Alpha3: "KIR", Name: "Kiribati", Aliases: []string{"Republic of Kiribati"}},
+	{Code: "KN", Alpha3: "KNA", Name: "Saint Kitts and Nevis"},
+	{Code: "KR", Alpha3: "KOR", Name: "South Korea", Aliases: []string{"Korea, Republic of", "Korea"}},
+	{Code: "KW", Alpha3: "KWT", Name: "Kuwait", Aliases: []string{"State of Kuwait"}},
+	{Code: "LA", Alpha3: "LAO", Name: "Laos", Aliases: []string{"Lao People's Democratic Republic"}},
+	{Code: "LB", Alpha3: "LBN", Name: "Lebanon", Aliases: []string{"Lebanese Republic"}},
+	{Code: "LR", Alpha3: "LBR", Name: "Liberia", Aliases: []string{"Republic of Liberia"}},
+	{Code: "LY", Alpha3: "LBY", Name: "Libya"},
+	{Code: "LC", Alpha3: "LCA", Name: "Saint Lucia"},
+	{Code: "LI", Alpha3: "LIE", Name: "Liechtenstein", Aliases: []string{"Principality of Liechtenstein"}},
+	{Code: "LK", Alpha3: "LKA", Name: "Sri Lanka", Aliases: []string{"Democratic Socialist Republic of Sri Lanka"}},
+	{Code: "LS", Alpha3: "LSO", Name: "Lesotho", Aliases: []string{"Kingdom of Lesotho"}},
+	{Code: "LT", Alpha3: "LTU", Name: "Lithuania", Aliases: []string{"Republic of Lithuania"}},
+	{Code: "LU", Alpha3: "LUX", Name: "Luxembourg", Aliases: []string{"Grand Duchy of Luxembourg"}},
+	{Code: "LV", Alpha3: "LVA", Name: "Latvia", Aliases: []string{"Republic of Latvia"}},
+	{Code: "MO", Alpha3: "MAC", Name: "Macao", Aliases: []string{"Macao Special Administrative Region of China"}},
+	{Code: "MF", Alpha3: "MAF", Name: "Saint Martin (French part)"},
+	{Code: "MA", Alpha3: "MAR", Name: "Morocco", Aliases: []string{"Kingdom of Morocco"}},
+	{Code: "MC", Alpha3: "MCO", Name: "Monaco", Aliases: []string{"Principality of Monaco"}},
+	{Code: "MD", Alpha3: "MDA", Name: "Moldova", Aliases: []string{"Moldova, Republic of", "Republic of Moldova"}},
+	{Code: "MG", Alpha3: "MDG", Name: "Madagascar", Aliases: []string{"Republic of Madagascar"}},
+	{Code: "MV", Alpha3: "MDV", Name: "Maldives", Aliases: []string{"Republic of Maldives"}},
+	{Code: "MX", Alpha3: "MEX", Name: "Mexico", Aliases: []string{"United Mexican States"}},
+	{Code: "MH", Alpha3: "MHL", Name: "Marshall Islands", Aliases: []string{"Republic of the Marshall Islands"}},
+	{Code: "MK", Alpha3: "MKD", Name: "North Macedonia", Aliases: []string{"Republic of North Macedonia", "Macedonia"}},
+	{Code: "ML", Alpha3: "MLI", Name: "Mali", Aliases: []string{"Republic of Mali"}},
+	{Code: "MT", Alpha3: "MLT", Name: "Malta", Aliases: []string{"Republic of Malta"}},
+	{Code: "MM", Alpha3: "MMR", Name: "Myanmar", Aliases: []string{"Republic of Myanmar"}},
+	{Code: "ME", Alpha3: "MNE", Name: "Montenegro"},
+	{Code: "MN", Alpha3: "MNG", Name: "Mongolia"},
+	{Code: "MP", Alpha3: "MNP", Name: "Northern Mariana Islands", Aliases: []string{"Commonwealth of the Northern Mariana Islands"}},
+	{Code: "MZ", Alpha3: "MOZ", Name: "Mozambique", Aliases: []string{"Republic of Mozambique"}},
+	{Code: "MR", Alpha3: "MRT", Name: "Mauritania", Aliases: []string{"Islamic Republic of Mauritania"}},
+	{Code: "MS", Alpha3: "MSR", Name: "Montserrat"},
+	{Code: "MQ", Alpha3: "MTQ", Name: "Martinique"},
+	{Code: "MU", Alpha3: "MUS", Name: "Mauritius", Aliases: []string{"Republic of Mauritius"}},
+	{Code: "MW", Alpha3: "MWI", Name: "Malawi", Aliases: []string{"Republic of Malawi"}},
+	{Code: "MY", Alpha3: "MYS", Name: "Malaysia"},
+	{Code: "YT", Alpha3: "MYT", Name: "Mayotte"},
+	{Code: "NA", Alpha3: "NAM", Name: "Namibia", Aliases: []string{"Republic of Namibia"}},
+	{Code: "NC", Alpha3: "NCL", Name: "New Caledonia"},
+	{Code: "NE", Alpha3: "NER", Name: "Niger", Aliases: []string{"Republic of the Niger"}},
+	{Code: "NF", Alpha3: "NFK", Name: "Norfolk Island"},
+	{Code: "NG", Alpha3: "NGA", Name: "Nigeria", Aliases: []string{"Federal Republic of Nigeria"}},
+	{Code: "NI", Alpha3: "NIC", Name: "Nicaragua", Aliases: []string{"Republic of Nicaragua"}},
+	{Code: "NU", Alpha3: "NIU", Name: "Niue"},
+	{Code: "NL", Alpha3: "NLD", Name: "Netherlands", Aliases: []string{"Kingdom of the Netherlands", "Holland"}},
+	{Code: "NO", Alpha3: "NOR", Name: "Norway", Aliases: []string{"Kingdom of Norway"}},
+	{Code: "NP", Alpha3: "NPL", Name: "Nepal", Aliases: []string{"Federal Democratic Republic of Nepal"}},
+	{Code: "NR", Alpha3: "NRU", Name: "Nauru", Aliases: []string{"Republic of Nauru"}},
+	{Code: "NZ", Alpha3: "NZL", Name: "New Zealand"},
+	{Code: "OM", Alpha3: "OMN", Name: "Oman", Aliases: []string{"Sultanate of Oman"}},
+	{Code: "PK", Alpha3: "PAK", Name: "Pakistan", Aliases: []string{"Islamic Republic of Pakistan"}},
+	{Code: "PA", Alpha3: "PAN", Name: "Panama", Aliases: []string{"Republic of Panama"}},
+	{Code: "PN", Alpha3: "PCN", Name: "Pitcairn"},
+	{Code: "PE", Alpha3: "PER", Name: "Peru", Aliases: []string{"Republic of Peru"}},
+	{Code: "PH", Alpha3: "PHL", Name: "Philippines", Aliases: []string{"Republic of the Philippines"}},
+	{Code: "PW", Alpha3: "PLW", Name: "Palau", Aliases: []string{"Republic of Palau"}},
+	{Code: "PG", Alpha3: "PNG", Name: "Papua New Guinea", Aliases: []string{"Independent State of Papua New Guinea"}},
+	{Code: "PL", Alpha3: "POL", Name: "Poland", Aliases: []string{"Republic of Poland"}},
+	{Code: "PR", Alpha3: "PRI", Name: "Puerto Rico"},
+	{Code: "KP", Alpha3: "PRK", Name: "North Korea", Aliases: []string{"Korea, Democratic People's Republic of", "Democratic People's Republic of Korea"}},
+	{Code: "PT", Alpha3: "PRT", Name: "Portugal", Aliases: []string{"Portuguese Republic"}},
+	{Code: "PY", Alpha3: "PRY", Name: "Paraguay", Aliases: []string{"Republic of Paraguay"}},
+	{Code: "PS", Alpha3: "PSE", Name: "Palestine, State of", Aliases: []string{"the State of Palestine"}},
+	{Code: "PF", Alpha3: "PYF", Name: "French Polynesia"},
+	{Code: "QA", Alpha3: "QAT", Name: "Qatar", Aliases: []string{"State of Qatar"}},
+	{Code: "RE", Alpha3: "REU", Name: "Réunion"},
+	{Code: "RO", Alpha3: "ROU", Name: "Romania"},
+	{Code: "RU", Alpha3: "RUS", Name: "Russian Federation", Aliases: []string{"Russia"}},
+	{Code: "RW", Alpha3: "RWA", Name: "Rwanda", Aliases: []string{"Rwandese Republic"}},
+	{Code: "SA", Alpha3: "SAU", Name: "Saudi Arabia", Aliases: []string{"Kingdom of Saudi Arabia"}},
+	{Code: "SD", Alpha3: "SDN", Name: "Sudan", Aliases: []string{"Republic of the Sudan"}},
+	{Code: "SN", Alpha3: "SEN", Name: "Senegal", Aliases: []string{"Republic of Senegal"}},
+	{Code: "SG", Alpha3: "SGP", Name: "Singapore", Aliases: []string{"Republic of Singapore"}},
+	{Code: "GS", Alpha3: "SGS", Name: "South Georgia and the South Sandwich Islands"},
+	{Code: "SH", Alpha3: "SHN", Name: "Saint Helena, Ascension and Tristan da Cunha"},
+	{Code: "SJ", Alpha3: "SJM", Name: "Svalbard and Jan Mayen"},
+	{Code: "SB", Alpha3: "SLB", Name: "Solomon Islands"},
+	{Code: "SL", Alpha3: "SLE", Name: "Sierra Leone", Aliases: []string{"Republic of Sierra Leone"}},
+	{Code: "SV", Alpha3: "SLV", Name: "El Salvador", Aliases: []string{"Republic of El Salvador"}},
+	{Code: "SM", Alpha3: "SMR", Name: "San Marino", Aliases: []string{"Republic of San Marino"}},
+	{Code: "SO", Alpha3: "SOM", Name: "Somalia", Aliases: []string{"Federal Republic of Somalia"}},
+	{Code: "PM", Alpha3: "SPM", Name: "Saint Pierre and Miquelon"},
+	{Code: "RS", Alpha3: "SRB", Name: "Serbia", Aliases: []string{"Republic of Serbia"}},
+	{Code: "SS", Alpha3: "SSD", Name: "South Sudan", Aliases: []string{"Republic of South Sudan"}},
+	{Code: "ST", Alpha3: "STP", Name: "Sao Tome and Principe", Aliases: []string{"Democratic Republic of Sao Tome and Principe"}},
+	{Code: "SR", Alpha3: "SUR", Name: "Suriname", Aliases: []string{"Republic of Suriname"}},
+	{Code: "SK", Alpha3: "SVK", Name: "Slovakia", Aliases: []string{"Slovak Republic"}},
+	{Code: "SI", Alpha3: "SVN", Name: "Slovenia", Aliases: []string{"Republic of Slovenia"}},
+	{Code: "SE", Alpha3: "SWE", Name: "Sweden", Aliases: []string{"Kingdom of Sweden"}},
+	{Code: "SZ", Alpha3: "SWZ", Name: "Eswatini", Aliases: []string{"Kingdom of Eswatini"}},
+	{Code: "SX", Alpha3: "SXM", Name: "Sint Maarten (Dutch part)"},
+	{Code: "SC", Alpha3: "SYC", Name: "Seychelles", Aliases: []string{"Republic of Seychelles"}},
+	{Code: "SY", Alpha3: "SYR", Name: "Syria", Aliases: []string{"Syrian Arab Republic"}},
+	{Code: "TC", Alpha3: "TCA", Name: "Turks and Caicos Islands"},
+	{Code: "TD", Alpha3: "TCD", Name: "Chad", Aliases: []string{"Republic of Chad"}},
+	{Code: "TG", Alpha3: "TGO", Name: "Togo", Aliases: []string{"Togolese Republic"}},
+	{Code: "TH", Alpha3: "THA", Name: "Thailand", Aliases: []string{"Kingdom of Thailand"}},
+	{Code: "TJ", Alpha3: "TJK", Name: "Tajikistan", Aliases: []string{"Republic of Tajikistan"}},
+	{Code: "TK", Alpha3: "TKL", Name: "Tokelau"},
+	{Code: "TM", Alpha3: "TKM", Name: "Turkmenistan"},
+	{Code: "TL", Alpha3: "TLS", Name: "Timor-Leste", Aliases: []string{"Democratic Republic of Timor-Leste"}},
+	{Code: "TO", Alpha3: "TON", Name: "Tonga", Aliases: []string{"Kingdom of Tonga"}},
+	{Code: "TT", Alpha3: "TTO", Name: "Trinidad and Tobago", Aliases: []string{"Republic of Trinidad and Tobago"}},
+	{Code: "TN", Alpha3: "TUN", Name: "Tunisia", Aliases: []string{"Republic of Tunisia"}},
+	{Code: "TR", Alpha3: "TUR", Name: "Türkiye", Aliases: []string{"Republic of Türkiye"}},
+	{Code: "TV", Alpha3: "TUV", Name: "Tuvalu"},
+	{Code: "TW", Alpha3: "TWN", Name: "Taiwan", Aliases: []string{"Taiwan, Province of China"}},
+	{Code: "TZ", Alpha3: "TZA", Name: "Tanzania", Aliases: []string{"Tanzania, United Republic of", "United Republic of Tanzania"}},
+	{Code: "UG", Alpha3: "UGA", Name: "Uganda", Aliases: []string{"Republic of Uganda"}},
+	{Code: "UA", Alpha3: "UKR", Name: "Ukraine"},
+	{Code: "UM", Alpha3: "UMI", Name: "United States Minor Outlying Islands"},
+	{Code: "UY", Alpha3: "URY", Name: "Uruguay", Aliases: []string{"Eastern Republic of Uruguay"}},
+	{Code: "US", Alpha3: "USA", Name: "United States", Aliases: []string{"United States of America", "USA"}},
+	{Code: "UZ", Alpha3: "UZB", Name: "Uzbekistan", Aliases: []string{"Republic of Uzbekistan"}},
+	{Code: "VA", Alpha3: "VAT", Name: "Holy See (Vatican City State)"},
+	{Code: "VC", Alpha3: "VCT", Name: "Saint Vincent and the Grenadines"},
+	{Code: "VE", Alpha3: "VEN", Name: "Venezuela", Aliases: []string{"Venezuela, Bolivarian Republic of", "Bolivarian Republic of Venezuela"}},
+	{Code: "VG", Alpha3: "VGB", Name: "Virgin Islands, British", Aliases: []string{"British Virgin Islands"}},
+	{Code: "VI", Alpha3: "VIR", Name: "Virgin Islands, U.S.", Aliases: []string{"Virgin Islands of the United States"}},
+	{Code: "VN", Alpha3: "VNM", Name: "Vietnam", Aliases: []string{"Viet Nam", "Socialist Republic of Viet Nam"}},
+	{Code: "VU", Alpha3: "VUT", Name: "Vanuatu", Aliases: []string{"Republic of Vanuatu"}},
+	{Code: "WF", Alpha3: "WLF", Name: "Wallis and Futuna"},
+	{Code: "WS", Alpha3: "WSM", Name: "Samoa", Aliases: []string{"Independent State of Samoa"}},
+	{Code: "YE", Alpha3: "YEM", Name: "Yemen", Aliases: []string{"Republic of Yemen"}},
+	{Code: "ZA", Alpha3: "ZAF", Name: "South Africa", Aliases: []string{"Republic of South Africa"}},
+	{Code: "ZM", Alpha3: "ZMB", Name: "Zambia", Aliases: []string{"Republic of Zambia"}},
+	{Code: "ZW", Alpha3: "ZWE", Name: "Zimbabwe", Aliases: []string{"Republic of Zimbabwe"}},
+
+	// United Kingdom nations
+	{Code: "GB-ENG", Name: "England"},
+	{Code: "GB-NIR", Name: "Northern Ireland"},
+	{Code: "GB-SCT", Name: "Scotland"},
+	{Code: "GB-WLS", Name: "Wales"},
+
+	// Regional teams, using a user-assigned code
+	{Code: "XM", Name: "Middle East"},
+}
@@ -0,0 +1,108 @@
+package country
+
+import (
+	"path"
+	"strings"
+)
+
+// A Country is an entry of the country table.
+type Country struct {
+	Code    string
+	Alpha3  string
+	Name    string
+	Aliases []string
+}
+
+// Names returns the name of the country followed by its aliases.
+func (c Country) Names() []string {
+	return append([]string{c.Name}, c.Aliases...)
+}
+
+// Lookup returns the country whose code, name or alias matches the given
+// string, ignoring case. Flag image paths like "img/flags/fr.png" are
+// accepted too.
+func Lookup(s string) (Country, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Country{}, false
+	}
+
+	// Try the raw string first, then the base name of a flag image.
+	for _, key := range []string{s, strings.TrimSuffix(path.Base(s), path.Ext(s))} {
+		for _, c := range Countries {
+			if strings.EqualFold(key, c.Code) || strings.EqualFold(key, c.Alpha3) {
+				return c, true
+			}
+			for _, n := range c.Names() {
+				if strings.EqualFold(key, n) {
+					return c, true
+				}
+			}
+		}
+	}
+
+	return Country{}, false
+}
+
+// Split splits a team string of the form "<country> <name>" into the
+// country and the team name. The longest matching country name or alias
+// wins, so "Northern Ireland Foo" isn't parsed as an irish team.
+func Split(team string) (Country, string, bool) {
+	team = strings.TrimSpace(team)
+
+	var found Country
+	var length int
+	for _, c := range Countries {
+		for _, n := range c.Names() {
+			if len(n) <= length || !HasPrefix(team, n) {
+				continue
+			}
+
+			found = c
+			length = len(n)
+		}
+	}
+
+	if length == 0 {
+		return Country{}, team, false
+	}
+
+	return found, strings.TrimSpace(team[length:]), true
+}
+
+// ParseTeam extracts the country and the name of a team. The country code
+// found by the crawler in the page markup is used if available, else the
+// country is deduced from the beginning of the team string. Teams whose
+// country can't be found are kept whole.
+func ParseTeam(team, code string) (Country, string, bool) {
+	if c, found := Lookup(code); found {
+		return c, c.Trim(team), true
+	}
+
+	return Split(team)
+}
+
+// Trim removes the name or alias of the country from the beginning of the
+// team string, if present.
+func (c Country) Trim(team string) string {
+	team = strings.TrimSpace(team)
+
+	var length int
+	for _, n := range c.Names() {
+		if len(n) > length && HasPrefix(team, n) {
+			length = len(n)
+		}
+	}
+
+	return strings.TrimSpace(team[length:])
+}
+
+// HasPrefix tests whether the team string begins with the given country
+// name, as a whole word.
+func HasPrefix(team, name string) bool {
+	if !strings.HasPrefix(team, name) {
+		return false
+	}
+
+	return len(team) == len(name) || team[len(name)] == ' '
+}
@@ -0,0 +1,162 @@
+package country
+
+import (
+	"testing"
+)
+
+// teamCountries are the countries of the teams of the existing data, as
+// written on the WTC website, with their codes.
+var teamCountries = []struct {
+	name, code string
+}{
+	{"Australia", "AU"},
+	{"Austria", "AT"},
+	{"Belgium", "BE"},
+	{"Canada", "CA"},
+	{"China", "CN"},
+	{"Czech Republic", "CZ"},
+	{"Denmark", "DK"},
+	{"England", "GB-ENG"},
+	{"Finland", "FI"},
+	{"France", "FR"},
+	{"Germany", "DE"},
+	{"Greece", "GR"},
+	{"Hungary", "HU"},
+	{"Ireland", "IE"},
+	{"Italy", "IT"},
+	{"Latvia", "LV"},
+	{"Middle East", "XM"},
+	{"Netherlands", "NL"},
+	{"Northern Ireland", "GB-NIR"},
+	{"Norway", "NO"},
+	{"Poland", "PL"},
+	{"Portugal", "PT"},
+	{"Russia", "RU"},
+	{"Scotland", "GB-SCT"},
+	{"Slovenia", "SI"},
+	{"Spain", "ES"},
+	{"Sweden", "SE"},
+	{"Switzerland", "CH"},
+	{"UAE", "AE"},
+	{"USA", "US"},
+	{"Wales", "GB-WLS"},
+}
+
+func TestTeamCountries(t *testing.T) {
+	for _, c := range teamCountries {
+		country, found := Lookup(c.name)
+		if !found || country.Code != c.code {
+			t.Errorf("Lookup(%q): expected %s, got %q %t", c.name, c.code, country.Code, found)
+		}
+
+		var team = c.name + " Red"
+		country, name, found := Split(team)
+		if !found || country.Code != c.code || name != "Red" {
+			t.Errorf("Split(%q): expected %s Red, got %q %q %t", team, c.code, country.Code, name, found)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	for _, c := range []struct {
+		s     string
+		code  string
+		found bool
+	}{
+		{"FR", "FR", true},
+		{"fr", "FR", true},
+		{"FRA", "FR", true},
+		{"france", "FR", true},
+		{" France ", "FR", true},
+		{"French Republic", "FR", true},
+		{"img/flags/fr.png", "FR", true},
+		{"/img/flags/gb-sct.png", "GB-SCT", true},
+		{"GB-ENG", "GB-ENG", true},
+		{"gb-nir", "GB-NIR", true},
+		{"GB-WLS", "GB-WLS", true},
+		{"XM", "XM", true},
+		{"middle east", "XM", true},
+		{"GB", "GB", true},
+		{"Great Britain", "GB", true},
+		{"", "", false},
+		{"Atlantis", "", false},
+		{"img/flags/xx.png", "", false},
+	} {
+		country, found := Lookup(c.s)
+		if found != c.found || country.Code != c.code {
+			t.Errorf("Lookup(%q): expected %q %t, got %q %t", c.s, c.code, c.found, country.Code, found)
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	for _, c := range []struct {
+		team  string
+		code  string
+		name  string
+		found bool
+	}{
+		{"Northern Ireland Green", "GB-NIR", "Green", true},
+		{"Ireland Green", "IE", "Green", true},
+		{"United States of America Stars", "US", "Stars", true},
+		{"United States Stripes", "US", "Stripes", true},
+		{"Middle East Falcons", "XM", "Falcons", true},
+		{"  England Lions  ", "GB-ENG", "Lions", true},
+		{"England", "GB-ENG", "", true},
+		// The country must be a whole word.
+		{"Englandia Red", "", "Englandia Red", false},
+		{"Atlantis Red", "", "Atlantis Red", false},
+		{"", "", "", false},
+	} {
+		country, name, found := Split(c.team)
+		if found != c.found || country.Code != c.code || name != c.name {
+			t.Errorf("Split(%q): expected %q %q %t, got %q %q %t", c.team, c.code, c.name, c.found, country.Code, name, found)
+		}
+	}
+}
+
+func TestParseTeam(t *testing.T) {
+	for _, c := range []struct {
+		team, code string
+		country    string
+		name       string
+		found      bool
+	}{
+		// The code of the markup wins over the team string.
+		{"Ireland Green", "GB-NIR", "GB-NIR", "Ireland Green", true},
+		{"Northern Ireland Green", "img/flags/gb-nir.png", "GB-NIR", "Green", true},
+		{"France Blue", "FR", "FR", "Blue", true},
+		{"Les Bleus", "FR", "FR", "Les Bleus", true},
+		// Without a known code, the team string is split.
+		{"Northern Ireland Green", "", "GB-NIR", "Green", true},
+		{"Scotland Thistle", "??", "GB-SCT", "Thistle", true},
+		{"Atlantis Red", "", "", "Atlantis Red", false},
+	} {
+		country, name, found := ParseTeam(c.team, c.code)
+		if found != c.found || country.Code != c.country || name != c.name {
+			t.Errorf("ParseTeam(%q, %q): expected %q %q %t, got %q %q %t", c.team, c.code, c.country, c.name, c.found, country.Code, name, found)
+		}
+	}
+}
+
+func TestTrim(t *testing.T) {
+	var us, _ = Lookup("US")
+	var gb, _ = Lookup("GB")
+	for _, c := range []struct {
+		country  Country
+		team     string
+		expected string
+	}{
+		{us, "United States of America Stars", "Stars"},
+		{us, "USA Stars", "Stars"},
+		{us, "Stars", "Stars"},
+		{us, "USAF Stars", "USAF Stars"},
+		{gb, "United Kingdom of Great Britain and Northern Ireland Red", "Red"},
+		{gb, "Great Britain Red", "Red"},
+		{gb, " UK ", ""},
+	} {
+		if name := c.country.Trim(c.team); name != c.expected {
+			t.Errorf("%s.Trim(%q): expected %q, got %q", c.country.Code, c.team, c.expected, name)
+		}
+	}
+}
@@ -1,72 +1,4 @@
-package main
-
-import (
-	"flag"
-	"fmt"
-	"logger"
-	"os"
-
-	"github.com/jmoiron/sqlx"
-	_ "github.com/mattn/go-sqlite3"
-)
-
-var (
-	log = logger.New(os.Stderr).With(logger.M{
-		"app": "checker",
-	})
-	database = flag.String("db", "data.sqlite", "database file")
-)
-
-func main() {
-	flag.Parse()
-
-	db, err := sqlx.Connect("sqlite3", *database)
-	if err != nil {
-		log.Error("opening database", logger.M{
-			"path": *database,
-			"err":  err,
-		})
-		return
-	}
-
-	// Find casters whose name in wrong
-	var casters = make([]string, 0, len(CastersFactions))
-	for caster, _ := range CastersFactions {
-		casters = append(casters, caster)
-	}
-	query, args, _ := sqlx.In(`
-		select distinct
-			name,
-			round,
-			zone,
-			caster
-		from list
-		join player on player.id = list.player_id
-		join report on report.list_id = list.id
-		join game on game.id = report.game_id
-		join match on match.id = game.match_id
-		where caster not in (?)
-		order by caster
-	`, casters)
-
-	var typos []struct {
-		Name   string
-		Round  int
-		Zone   int
-		Caster string
-	}
-	err = db.Select(&typos, query, args...)
-	if err != nil {
-		log.Error("unable to get typoed casters", logger.M{
-			"err": err,
-		})
-		return
-	}
-
-	for _, typo := range typos {
-		fmt.Println(typo)
-	}
-}
+package factions
 
 const (
 	Cygnar      = "cygnar"
@@ -83,7 +15,8 @@ const (
 	Minion      = "minion"
 )
 
-var CastersFactions = map[string]string{
+// Casters maps each caster, as written on the WTC website, to its faction.
+var Casters = map[string]string{
 	// Everblight
 	"Absylonia 2":        Everblight,
 	"Kallus 1":           Everblight,
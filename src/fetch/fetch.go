@@ -0,0 +1,334 @@
+package fetch
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrDisallowed is returned when the robots.txt of the site forbids the
+// retrieval of a page.
+var ErrDisallowed = errors.New("disallowed by robots.txt")
+
+// A StatusError is returned when the server answers with an unexpected
+// status.
+type StatusError struct {
+	Status int
+}
+
+func (e StatusError) Error() string {
+	return fmt.Sprintf("unexpected status %d %s", e.Status, http.StatusText(e.Status))
+}
+
+// A Client is a polite HTTP client: it identifies itself, waits between
+// requests, retries failed requests with an exponential backoff and respects
+// the robots.txt of the sites.
+type Client struct {
+	HTTP      *http.Client
+	UserAgent string
+	Retries   int
+	Backoff   time.Duration
+	Interval  time.Duration
+	Robots    bool
+	// Observe, if set, is called after each request with the status of the
+	// response, 0 if there was none, and the duration of the request.
+	Observe func(status int, elapsed time.Duration)
+
+	mu     sync.Mutex
+	next   time.Time
+	robots map[string]*hostRobots
+}
+
+// hostRobots are the robots.txt rules of a host, ready once they were
+// retrieved, so that the callers needing them meanwhile wait for the same
+// retrieval.
+type hostRobots struct {
+	ready chan struct{}
+	rules *robots
+	err   error
+}
+
+// New returns a client with the given timeout and user agent, and sensible
+// defaults otherwise.
+func New(timeout time.Duration, userAgent string) *Client {
+	return &Client{
+		HTTP: &http.Client{
+			Timeout: timeout,
+		},
+		UserAgent: userAgent,
+		Retries:   3,
+		Backoff:   time.Second,
+		Interval:  time.Second,
+		Robots:    true,
+		robots:    make(map[string]*hostRobots),
+	}
+}
+
+// Get retrieves the body of the page at the given URL. Network errors and
+// server errors are retried, client errors are not. The retrieval stops
+// as soon as the context is done.
+func (c *Client) Get(ctx context.Context, rawURL string) ([]byte, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Robots {
+		r, err := c.rules(ctx, u)
+		if err != nil {
+			return nil, err
+		}
+		if !r.allowed(u.RequestURI()) {
+			return nil, ErrDisallowed
+		}
+	}
+
+	return c.fetch(ctx, rawURL)
+}
+
+// fetch retrieves the body of the page, retrying network errors, server
+// errors and throttling with an exponential backoff.
+func (c *Client) fetch(ctx context.Context, rawURL string) ([]byte, error) {
+	var backoff = c.Backoff
+	for attempt := 0; ; attempt++ {
+		body, err := c.get(ctx, rawURL)
+		if err == nil {
+			return body, nil
+		}
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		if status, ok := err.(StatusError); ok && status.Status < 500 && status.Status != http.StatusTooManyRequests {
+			return nil, err
+		}
+
+		if attempt >= c.Retries {
+			return nil, err
+		}
+
+		err = sleep(ctx, backoff)
+		if err != nil {
+			return nil, err
+		}
+		backoff *= 2
+	}
+}
+
+// get does a single request, once the rate limit allows it.
+func (c *Client) get(ctx context.Context, rawURL string) ([]byte, error) {
+	err := c.wait(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("GET", rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("User-Agent", c.UserAgent)
+
+	var start = time.Now()
+	res, err := c.HTTP.Do(req)
+	if err != nil {
+		c.observe(0, start)
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	c.observe(res.StatusCode, start)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, StatusError{Status: res.StatusCode}
+	}
+
+	return body, nil
+}
+
+func (c *Client) observe(status int, start time.Time) {
+	if c.Observe != nil {
+		c.Observe(status, time.Since(start))
+	}
+}
+
+// wait blocks until the interval since the previous request is elapsed.
+func (c *Client) wait(ctx context.Context) error {
+	c.mu.Lock()
+	var now = time.Now()
+	var at = c.next
+	if at.Before(now) {
+		at = now
+	}
+	c.next = at.Add(c.Interval)
+	c.mu.Unlock()
+
+	return sleep(ctx, at.Sub(now))
+}
+
+// sleep waits for the given duration, or until the context is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	var timer = time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// rules returns the robots.txt rules of the host of the URL, retrieving them
+// on first use. A failed retrieval isn't kept, the next use tries again.
+func (c *Client) rules(ctx context.Context, u *url.URL) (*robots, error) {
+	var host = u.Scheme + "://" + u.Host
+
+	c.mu.Lock()
+	h, found := c.robots[host]
+	if !found {
+		h = &hostRobots{ready: make(chan struct{})}
+		c.robots[host] = h
+	}
+	c.mu.Unlock()
+
+	if found {
+		select {
+		case <-h.ready:
+			return h.rules, h.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	body, err := c.fetch(ctx, host+"/robots.txt")
+	if status, ok := err.(StatusError); ok && status.Status < 500 && status.Status != http.StatusTooManyRequests {
+		// A missing robots.txt allows everything.
+		body, err = nil, nil
+	}
+
+	c.mu.Lock()
+	if err != nil {
+		h.err = err
+		delete(c.robots, host)
+	} else {
+		h.rules = parseRobots(body, c.UserAgent)
+		if h.rules.delay > c.Interval {
+			c.Interval = h.rules.delay
+		}
+	}
+	c.mu.Unlock()
+
+	close(h.ready)
+	return h.rules, h.err
+}
+
+type robots struct {
+	allow    []string
+	disallow []string
+	delay    time.Duration
+}
+
+// allowed tests the path against the rules, the longest matching rule
+// winning.
+func (r *robots) allowed(path string) bool {
+	var allowed = true
+	var length = -1
+	for _, rules := range []struct {
+		prefixes []string
+		allow    bool
+	}{{r.disallow, false}, {r.allow, true}} {
+		for _, prefix := range rules.prefixes {
+			if strings.HasPrefix(path, prefix) && len(prefix) >= length {
+				allowed = rules.allow
+				length = len(prefix)
+			}
+		}
+	}
+	return allowed
+}
+
+// parseRobots reads the rules of a robots.txt file applying to the user
+// agent: the rules of the groups naming the agent if any, else the rules of
+// the "*" groups.
+func parseRobots(body []byte, userAgent string) *robots {
+	var agent = strings.ToLower(userAgent)
+	if i := strings.IndexAny(agent, "/ "); i >= 0 {
+		agent = agent[:i]
+	}
+
+	var specific, generic robots
+	var hasSpecific bool
+	var current []*robots
+	var inRules bool
+
+	var scanner = bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		var line = scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+
+		var parts = strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		var key = strings.ToLower(strings.TrimSpace(parts[0]))
+		var value = strings.TrimSpace(parts[1])
+
+		if key == "user-agent" {
+			// A user-agent line following rules starts a new group.
+			if inRules {
+				current = nil
+				inRules = false
+			}
+
+			var name = strings.ToLower(value)
+			switch {
+			case name == "*":
+				current = append(current, &generic)
+			case agent != "" && name != "" && strings.Contains(agent, name):
+				current = append(current, &specific)
+				hasSpecific = true
+			}
+			continue
+		}
+
+		inRules = true
+		for _, r := range current {
+			switch key {
+			case "allow":
+				if value != "" {
+					r.allow = append(r.allow, value)
+				}
+			case "disallow":
+				if value != "" {
+					r.disallow = append(r.disallow, value)
+				}
+			case "crawl-delay":
+				if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+					r.delay = time.Duration(seconds * float64(time.Second))
+				}
+			}
+		}
+	}
+
+	if hasSpecific {
+		return &specific
+	}
+	return &generic
+}
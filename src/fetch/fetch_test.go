@@ -0,0 +1,134 @@
+package fetch
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// site serves a robots.txt, failing the first requests of it with the given
+// statuses, and counts the requests of each path.
+type site struct {
+	mu       sync.Mutex
+	robots   string
+	failures []int
+	requests map[string]int
+}
+
+func (s *site) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests[r.URL.Path]++
+	if r.URL.Path != "/robots.txt" {
+		w.Write([]byte("page"))
+		return
+	}
+	if n := s.requests[r.URL.Path]; n <= len(s.failures) {
+		w.WriteHeader(s.failures[n-1])
+		return
+	}
+	w.Write([]byte(s.robots))
+}
+
+func newClient() *Client {
+	var c = New(time.Second, "wtcbot/1.0")
+	c.Backoff = time.Millisecond
+	c.Interval = 0
+	return c
+}
+
+func TestRobotsRetried(t *testing.T) {
+	var s = &site{
+		robots:   "User-agent: *\nDisallow: /private\n",
+		failures: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests},
+		requests: make(map[string]int),
+	}
+	var server = httptest.NewServer(s)
+	defer server.Close()
+
+	var c = newClient()
+	_, err := c.Get(context.Background(), server.URL+"/private/page")
+	if err != ErrDisallowed {
+		t.Fatalf("expected %v, got %v", ErrDisallowed, err)
+	}
+	if n := s.requests["/robots.txt"]; n != 3 {
+		t.Errorf("expected robots.txt to be requested 3 times, got %d", n)
+	}
+}
+
+func TestRobotsFetchedOnce(t *testing.T) {
+	const workers = 10
+
+	var s = &site{
+		robots:   "User-agent: *\nDisallow: /private\n",
+		requests: make(map[string]int),
+	}
+	var server = httptest.NewServer(s)
+	defer server.Close()
+
+	var c = newClient()
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.Get(context.Background(), server.URL+"/page")
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := s.requests["/robots.txt"]; n != 1 {
+		t.Errorf("expected robots.txt to be requested once, got %d", n)
+	}
+	if n := s.requests["/page"]; n != workers {
+		t.Errorf("expected the page to be requested %d times, got %d", workers, n)
+	}
+}
+
+func TestParseRobots(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		robots  string
+		path    string
+		allowed bool
+	}{
+		{
+			name:    "generic",
+			robots:  "User-agent: *\nDisallow: /private\n",
+			path:    "/private/page",
+			allowed: false,
+		},
+		{
+			name:    "specific over generic",
+			robots:  "User-agent: *\nDisallow: /\n\nUser-agent: wtcbot\nAllow: /\n",
+			path:    "/page",
+			allowed: true,
+		},
+		{
+			name:    "longest rule",
+			robots:  "User-agent: *\nDisallow: /private\nAllow: /private/public\n",
+			path:    "/private/public/page",
+			allowed: true,
+		},
+		{
+			name:    "empty user agent",
+			robots:  "User-agent:\nDisallow: /\n\nUser-agent: *\nDisallow: /private\n",
+			path:    "/page",
+			allowed: true,
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			var r = parseRobots([]byte(c.robots), "wtcbot/1.0")
+			if allowed := r.allowed(c.path); allowed != c.allowed {
+				t.Errorf("expected allowed %t for %s, got %t", c.allowed, c.path, allowed)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package identity
+
+import (
+	"atomicfile"
+	"encoding/json"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// An Identity is a person, known under one or more names.
+type Identity struct {
+	ID   int      `json:"id"`
+	Name string   `json:"name"`
+	Keys []string `json:"keys"`
+}
+
+// A Registry maps player names to identities. Names are compared once
+// normalized, and two players sharing a name in different teams of the same
+// event are kept apart.
+type Registry struct {
+	Identities []*Identity `json:"identities"`
+
+	keys map[string]*Identity
+	seen map[int]string
+	next int
+}
+
+// New returns an empty registry.
+func New() *Registry {
+	return &Registry{
+		keys: make(map[string]*Identity),
+		seen: make(map[int]string),
+		next: 1,
+	}
+}
+
+// Load reads a registry from the given file. A missing file gives an empty
+// registry.
+func Load(path string) (*Registry, error) {
+	r := New()
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	err = json.NewDecoder(file).Decode(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// The file may have been edited by hand, the new identities are given
+	// IDs after the highest one rather than after the count.
+	for _, i := range r.Identities {
+		for _, k := range i.Keys {
+			r.keys[k] = i
+		}
+		if i.ID >= r.next {
+			r.next = i.ID + 1
+		}
+	}
+
+	return r, nil
+}
+
+// Save writes the registry to the given file. The file is replaced at once,
+// so it is never left half-written.
+func (r *Registry) Save(path string) error {
+	file, err := atomicfile.Create(path)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(file)
+	enc.SetIndent("", "\t")
+	err = enc.Encode(r)
+	if err != nil {
+		file.Abort()
+		return err
+	}
+
+	return file.Commit()
+}
+
+// Resolve returns the identity of the player of the given name and team,
+// creating it if needed. Within an event, a name already resolved for
+// another team gives a distinct identity, bound to the team.
+func (r *Registry) Resolve(name, team string) *Identity {
+	// The teams are compared normalized too, as their names may be written
+	// differently from one page to the other.
+	n := Normalize(name)
+	team = Normalize(team)
+	teamKey := n + "@" + team
+
+	if i, found := r.keys[teamKey]; found {
+		r.seen[i.ID] = team
+		return i
+	}
+
+	if i, found := r.keys[n]; found {
+		if t, seen := r.seen[i.ID]; !seen || t == team {
+			r.seen[i.ID] = team
+			return i
+		}
+	}
+
+	// The name is either unknown, or already taken by a player of another
+	// team: the team is part of the key from now on.
+	key := n
+	if _, found := r.keys[n]; found {
+		key = teamKey
+	}
+
+	i := &Identity{
+		ID:   r.next,
+		Name: name,
+		Keys: []string{key},
+	}
+	r.next++
+	r.Identities = append(r.Identities, i)
+	r.keys[key] = i
+	r.seen[i.ID] = team
+	return i
+}
+
+// Normalize returns the comparison form of a name: lower case, without
+// diacritics and with whitespace collapsed.
+func Normalize(name string) string {
+	name = strings.ToLower(name)
+	name = folder.Replace(name)
+	name = strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Mn, r) {
+			return -1
+		}
+		return r
+	}, name)
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// folder replaces the lower case latin letters with diacritics by their
+// base letters.
+var folder = strings.NewReplacer(
+	"à", "a", "á", "a", "â", "a", "ã", "a", "ä", "a", "å", "a", "ā", "a", "ă", "a", "ą", "a",
+	"æ", "ae",
+	"ç", "c", "ć", "c", "ĉ", "c", "ċ", "c", "č", "c",
+	"ď", "d", "đ", "d", "ð", "d",
+	"è", "e", "é", "e", "ê", "e", "ë", "e", "ē", "e", "ĕ", "e", "ė", "e", "ę", "e", "ě", "e",
+	"ĝ", "g", "ğ", "g", "ġ", "g", "ģ", "g",
+	"ĥ", "h", "ħ", "h",
+	"ì", "i", "í", "i", "î", "i", "ï", "i", "ĩ", "i", "ī", "i", "ĭ", "i", "į", "i", "ı", "i",
+	"ĵ", "j",
+	"ķ", "k",
+	"ĺ", "l", "ļ", "l", "ľ", "l", "ŀ", "l", "ł", "l",
+	"ñ", "n", "ń", "n", "ņ", "n", "ň", "n",
+	"ò", "o", "ó", "o", "ô", "o", "õ", "o", "ö", "o", "ø", "o", "ō", "o", "ŏ", "o", "ő", "o",
+	"œ", "oe",
+	"ŕ", "r", "ŗ", "r", "ř", "r",
+	"ś", "s", "ŝ", "s", "ş", "s", "š", "s", "ș", "s",
+	"ß", "ss",
+	"ţ", "t", "ť", "t", "ŧ", "t", "ț", "t",
+	"þ", "th",
+	"ù", "u", "ú", "u", "û", "u", "ü", "u", "ũ", "u", "ū", "u", "ŭ", "u", "ů", "u", "ű", "u", "ų", "u",
+	"ŵ", "w",
+	"ý", "y", "ÿ", "y", "ŷ", "y",
+	"ź", "z", "ż", "z", "ž", "z",
+)
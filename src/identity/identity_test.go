@@ -0,0 +1,178 @@
+package identity
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveAfterGaps(t *testing.T) {
+	dir, err := ioutil.TempDir("", "identity")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var path = filepath.Join(dir, "identities.json")
+	err = ioutil.WriteFile(path, []byte(`{"identities": [
+		{"id": 1, "name": "Alice", "keys": ["alice"]},
+		{"id": 3, "name": "Bob", "keys": ["bob"]}
+	]}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if i := r.Resolve("Bob", "Team A"); i.ID != 3 {
+		t.Errorf("expected the known identity 3 for Bob, got %d", i.ID)
+	}
+
+	var ids = make(map[int]string)
+	for _, i := range r.Identities {
+		ids[i.ID] = i.Name
+	}
+	for _, name := range []string{"Carol", "Dave"} {
+		var i = r.Resolve(name, "Team B")
+		if other, taken := ids[i.ID]; taken {
+			t.Fatalf("%s got the ID %d of %s", name, i.ID, other)
+		}
+		ids[i.ID] = name
+	}
+
+	if i := r.Resolve("Carol", "Team B"); i.ID != 4 {
+		t.Errorf("expected the ID 4 for Carol, got %d", i.ID)
+	}
+	if i := r.Resolve("Dave", "Team B"); i.ID != 5 {
+		t.Errorf("expected the ID 5 for Dave, got %d", i.ID)
+	}
+
+	// Bob is bound to Team A for the event, the Bob of Team C is someone
+	// else, and must not share the bindings of an existing identity.
+	if i := r.Resolve("Bob", "Team C"); i.ID != 6 {
+		t.Errorf("expected the new ID 6 for the Bob of Team C, got %d", i.ID)
+	}
+}
+
+func TestResolveNew(t *testing.T) {
+	var r = New()
+	if i := r.Resolve("Alice", "Team A"); i.ID != 1 {
+		t.Errorf("expected the ID 1, got %d", i.ID)
+	}
+	if i := r.Resolve("alice ", "Team A"); i.ID != 1 {
+		t.Errorf("expected the normalized name to resolve to 1, got %d", i.ID)
+	}
+	if i := r.Resolve("Bob", "Team A"); i.ID != 2 {
+		t.Errorf("expected the ID 2, got %d", i.ID)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	for _, c := range []struct {
+		name, expected string
+	}{
+		{"Alice", "alice"},
+		{"  Jean   Dupont ", "jean dupont"},
+		{"Jérôme", "jerome"},
+		{"JÉRÔME", "jerome"},
+		{"Łukasz Żółć", "lukasz zolc"},
+		{"Søren Ærø", "soren aero"},
+		{"Straße", "strasse"},
+		{"Je\u0301ro\u0302me", "jerome"}, // combining accents
+		{"Þór", "thor"},
+	} {
+		if n := Normalize(c.name); n != c.expected {
+			t.Errorf("Normalize(%q): expected %q, got %q", c.name, c.expected, n)
+		}
+	}
+}
+
+func TestResolveTeams(t *testing.T) {
+	var r = New()
+	var alice = r.Resolve("Alice", "Équipe Rouge")
+
+	// The same player, with the name of her team written differently.
+	for _, team := range []string{"equipe rouge", "EQUIPE  ROUGE", "Equipe Rouge "} {
+		if i := r.Resolve("Alice", team); i.ID != alice.ID {
+			t.Errorf("%q: expected the ID %d of Alice, got %d", team, alice.ID, i.ID)
+		}
+	}
+
+	// Another player of the same name, in another team.
+	var other = r.Resolve("ALICE", "Team Blue")
+	if other.ID == alice.ID {
+		t.Fatalf("the Alice of Team Blue got the ID %d of the Alice of Équipe Rouge", alice.ID)
+	}
+	if len(other.Keys) != 1 || other.Keys[0] != "alice@team blue" {
+		t.Errorf("expected the key to include the team, got %v", other.Keys)
+	}
+
+	// Both keep their identities.
+	if i := r.Resolve("Alice", "team blue"); i.ID != other.ID {
+		t.Errorf("expected the ID %d of the Alice of Team Blue, got %d", other.ID, i.ID)
+	}
+	if i := r.Resolve("Alice", "Equipe Rouge"); i.ID != alice.ID {
+		t.Errorf("expected the ID %d of the Alice of Équipe Rouge, got %d", alice.ID, i.ID)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "identity")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var r = New()
+	var alice = r.Resolve("Alice", "Team A")
+	var bob = r.Resolve("Bob", "Team A")
+	var otherAlice = r.Resolve("Alice", "Team B")
+
+	var path = filepath.Join(dir, "identities.json")
+	err = r.Save(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Identities) != 3 {
+		t.Fatalf("expected 3 identities, got %d", len(loaded.Identities))
+	}
+
+	// The identities are found again in the next event, by name or by name
+	// and team.
+	for _, c := range []struct {
+		name, team string
+		expected   *Identity
+	}{
+		{"Alice", "Team A", alice},
+		{"bob", "Team C", bob},
+		{"Alice", "Team B", otherAlice},
+	} {
+		var i = loaded.Resolve(c.name, c.team)
+		if i.ID != c.expected.ID || i.Name != c.expected.Name {
+			t.Errorf("%s of %s: expected %d %s, got %d %s", c.name, c.team, c.expected.ID, c.expected.Name, i.ID, i.Name)
+		}
+	}
+
+	if i := loaded.Resolve("Carol", "Team A"); i.ID != 4 {
+		t.Errorf("expected the ID 4 for a new identity, got %d", i.ID)
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	r, err := Load(filepath.Join(os.TempDir(), "missing", "identities.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Identities) != 0 {
+		t.Errorf("expected an empty registry, got %d identities", len(r.Identities))
+	}
+}
@@ -0,0 +1,26 @@
+package interrupt
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"status"
+	"syscall"
+)
+
+// Context returns a context canceled on the first SIGINT or SIGTERM. A
+// second signal kills the program immediately, with the interrupted status.
+func Context() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var signals = make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		cancel()
+		<-signals
+		os.Exit(status.Interrupted)
+	}()
+
+	return ctx
+}
@@ -0,0 +1,87 @@
+package jsonl
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// An Error is a malformed line of the stream.
+type Error struct {
+	// Line is the number of the line, starting at 1.
+	Line int
+	// Offset is the byte offset of the start of the line.
+	Offset int64
+	// Err is the decoding error.
+	Err error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("line %d (byte %d): %s", e.Line, e.Offset, e.Err)
+}
+
+// A Reader reads a stream of JSON values, one per line. A malformed line
+// doesn't stop the reading: it is reported, copied to the quarantine writer
+// if any, and the reading resumes at the next line.
+type Reader struct {
+	// Quarantine receives the malformed lines as is.
+	Quarantine io.Writer
+
+	r      *bufio.Reader
+	line   int
+	offset int64
+}
+
+// NewReader returns a reader of the given stream.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{
+		r: bufio.NewReader(r),
+	}
+}
+
+// Read decodes the next value into v. Blank lines are skipped, and io.EOF is
+// returned at the end of the stream. A malformed line gives an *Error, other
+// errors come from the underlying reader or the quarantine writer.
+func (r *Reader) Read(v interface{}) error {
+	for {
+		raw, err := r.r.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if len(raw) == 0 && err == io.EOF {
+			return io.EOF
+		}
+
+		r.line++
+		var offset = r.offset
+		r.offset += int64(len(raw))
+
+		var line = bytes.TrimSpace(raw)
+		if len(line) == 0 {
+			continue
+		}
+
+		decodeErr := json.Unmarshal(line, v)
+		if decodeErr == nil {
+			return nil
+		}
+
+		if r.Quarantine != nil {
+			if raw[len(raw)-1] != '\n' {
+				raw = append(raw, '\n')
+			}
+			_, err := r.Quarantine.Write(raw)
+			if err != nil {
+				return err
+			}
+		}
+
+		return &Error{
+			Line:   r.line,
+			Offset: offset,
+			Err:    decodeErr,
+		}
+	}
+}
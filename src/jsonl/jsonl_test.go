@@ -0,0 +1,159 @@
+package jsonl
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+type value struct {
+	N int    `json:"n"`
+	S string `json:"s"`
+}
+
+// readAll reads the values of the input until its end, along with the
+// malformed lines.
+func readAll(t *testing.T, r *Reader) ([]value, []*Error) {
+	var values []value
+	var errs []*Error
+	for {
+		var v value
+		err := r.Read(&v)
+		if err == io.EOF {
+			return values, errs
+		}
+		if malformed, ok := err.(*Error); ok {
+			errs = append(errs, malformed)
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		values = append(values, v)
+	}
+}
+
+func TestRead(t *testing.T) {
+	for _, c := range []struct {
+		name       string
+		input      string
+		values     []value
+		errs       []Error
+		quarantine string
+	}{
+		{
+			name:   "valid",
+			input:  "{\"n\": 1}\n{\"n\": 2}\n",
+			values: []value{{N: 1}, {N: 2}},
+		},
+		{
+			name:   "blank lines",
+			input:  "\n{\"n\": 1}\n  \n\n{\"n\": 2}\n",
+			values: []value{{N: 1}, {N: 2}},
+		},
+		{
+			name:   "no trailing newline",
+			input:  "{\"n\": 1}\n{\"n\": 2}",
+			values: []value{{N: 1}, {N: 2}},
+		},
+		{
+			name:       "malformed line",
+			input:      "{\"n\": 1}\n{\"n\": tw\n{\"n\": 3}\n",
+			values:     []value{{N: 1}, {N: 3}},
+			errs:       []Error{{Line: 2, Offset: 9}},
+			quarantine: "{\"n\": tw\n",
+		},
+		{
+			name:       "malformed lines in a row",
+			input:      "nope\n\n{\"n\": 1}\n[\n{\"n\": \"x\"}\n{\"n\": 2}\n",
+			values:     []value{{N: 1}, {N: 2}},
+			errs:       []Error{{Line: 1, Offset: 0}, {Line: 4, Offset: 15}, {Line: 5, Offset: 17}},
+			quarantine: "nope\n[\n{\"n\": \"x\"}\n",
+		},
+		{
+			name:       "malformed last line without newline",
+			input:      "{\"n\": 1}\n{\"n\":",
+			values:     []value{{N: 1}},
+			errs:       []Error{{Line: 2, Offset: 9}},
+			quarantine: "{\"n\":\n",
+		},
+		{
+			name:       "windows line endings",
+			input:      "{\"n\": 1}\r\nbad\r\n{\"n\": 2}\r\n",
+			values:     []value{{N: 1}, {N: 2}},
+			errs:       []Error{{Line: 2, Offset: 10}},
+			quarantine: "bad\r\n",
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			var quarantine bytes.Buffer
+			var r = NewReader(strings.NewReader(c.input))
+			r.Quarantine = &quarantine
+
+			values, errs := readAll(t, r)
+			if len(values) != len(c.values) {
+				t.Fatalf("expected %v, got %v", c.values, values)
+			}
+			for i := range values {
+				if values[i] != c.values[i] {
+					t.Errorf("expected %v, got %v", c.values, values)
+				}
+			}
+
+			if len(errs) != len(c.errs) {
+				t.Fatalf("expected %d malformed lines, got %v", len(c.errs), errs)
+			}
+			for i, err := range errs {
+				if err.Line != c.errs[i].Line || err.Offset != c.errs[i].Offset || err.Err == nil {
+					t.Errorf("expected line %d at byte %d, got %s", c.errs[i].Line, c.errs[i].Offset, err)
+				}
+			}
+
+			if quarantine.String() != c.quarantine {
+				t.Errorf("expected the quarantine %q, got %q", c.quarantine, quarantine.String())
+			}
+		})
+	}
+}
+
+// TestReadLongLines reads lines much longer than the buffer of the reader,
+// valid and malformed.
+func TestReadLongLines(t *testing.T) {
+	var long = strings.Repeat("x", 1<<20)
+	var bad = "{\"s\": \"" + long + "\n"
+	var input = "{\"s\": \"" + long + "\"}\n" + bad + "{\"n\": 3}\n"
+
+	var quarantine bytes.Buffer
+	var r = NewReader(strings.NewReader(input))
+	r.Quarantine = &quarantine
+
+	values, errs := readAll(t, r)
+	if len(values) != 2 || values[0].S != long || values[1].N != 3 {
+		t.Errorf("expected the long value and 3, got %d values", len(values))
+	}
+	if len(errs) != 1 || errs[0].Line != 2 || errs[0].Offset != int64(len(long)+10) {
+		t.Errorf("expected line 2 at byte %d, got %v", len(long)+10, errs)
+	}
+	if quarantine.String() != bad {
+		t.Errorf("expected the long line to be quarantined, got %d bytes", quarantine.Len())
+	}
+}
+
+// failingWriter fails every write.
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, io.ErrShortWrite
+}
+
+func TestReadQuarantineError(t *testing.T) {
+	var r = NewReader(strings.NewReader("bad\n{\"n\": 1}\n"))
+	r.Quarantine = failingWriter{}
+
+	var v value
+	err := r.Read(&v)
+	if err != io.ErrShortWrite {
+		t.Errorf("expected the error of the quarantine, got %v", err)
+	}
+}
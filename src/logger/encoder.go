@@ -0,0 +1,76 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/go-logfmt/logfmt"
+)
+
+// An Encoder writes the fields of a log line in a given format.
+type Encoder interface {
+	Encode(w io.Writer, data M) error
+}
+
+// Encoders are the available encoders, by format name.
+var Encoders = map[string]Encoder{
+	"logfmt": LogfmtEncoder{},
+	"json":   JSONEncoder{},
+}
+
+// LogfmtEncoder writes the fields in logfmt format, sorted by key. Values are
+// flattened to their string form.
+type LogfmtEncoder struct{}
+
+// Encode implements Encoder.
+func (LogfmtEncoder) Encode(w io.Writer, data M) error {
+	// Get each field and sort them.
+	fields := make([]string, 0, len(data))
+	for f := range data {
+		fields = append(fields, f)
+	}
+	sort.Strings(fields)
+
+	var enc = logfmt.NewEncoder(w)
+	for _, f := range fields {
+		err := enc.EncodeKeyval(f, fmt.Sprint(data[f]))
+		if err != nil {
+			return err
+		}
+	}
+	return enc.EndRecord()
+}
+
+// JSONEncoder writes the fields as a JSON object on a single line, sorted by
+// key. Values keep their type and nesting, except errors which are written as
+// their message, and values which can't be encoded, which are flattened to
+// their string form.
+type JSONEncoder struct{}
+
+// Encode implements Encoder.
+func (JSONEncoder) Encode(w io.Writer, data M) error {
+	var values = make(map[string]interface{}, len(data))
+	for k, v := range data {
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		values[k] = v
+	}
+
+	var enc = json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+
+	// The line is only written if every value could be encoded, else the
+	// offending values are flattened and the line encoded again.
+	if enc.Encode(values) == nil {
+		return nil
+	}
+	for k, v := range values {
+		if _, err := json.Marshal(v); err != nil {
+			values[k] = fmt.Sprint(v)
+		}
+	}
+	return enc.Encode(values)
+}
@@ -0,0 +1,289 @@
+package logger
+
+import (
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rotatedLayout is the layout of the suffix of the rotated files, which sort
+// in the order of their rotation.
+const rotatedLayout = "20060102T150405.000000000"
+
+// A RotatingFile is a log file which is rotated once too big or too old. The
+// rotated files are suffixed by the time of their rotation, optionally
+// compressed, and only the most recent ones are kept.
+type RotatingFile struct {
+	// MaxSize is the size in bytes from which the file is rotated, 0 for no
+	// limit.
+	MaxSize int64
+	// MaxAge is the duration after which the file is rotated, 0 for no limit.
+	MaxAge time.Duration
+	// Compress gzips the rotated files.
+	Compress bool
+	// Backups is the number of rotated files kept, 0 to keep them all.
+	Backups int
+
+	path    string
+	mu      sync.Mutex
+	file    *os.File
+	size    int64
+	created time.Time
+
+	// The rotated files are compressed and pruned in the background, in the
+	// order of their rotation, by a single worker. The first error met is
+	// returned by Close.
+	bg      sync.Mutex
+	queue   []string
+	working bool
+	done    sync.WaitGroup
+	bgErr   error
+}
+
+// OpenFile opens the log file at the given path, appending to it if it
+// exists.
+func OpenFile(path string) (*RotatingFile, error) {
+	var f = &RotatingFile{
+		path: path,
+	}
+	return f, f.open()
+}
+
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	f.file = file
+	f.size = info.Size()
+	f.created = f.creation()
+	return nil
+}
+
+// createdPath returns the path of the file recording the creation time of
+// the log file, hidden next to it so it isn't taken for a rotated file.
+func (f *RotatingFile) createdPath() string {
+	return filepath.Join(filepath.Dir(f.path), "."+filepath.Base(f.path)+".created")
+}
+
+// creation returns the creation time of the opened file, the age of the file
+// being counted from it. Neither the modification time, updated by each
+// write, nor the opening time, reset when the program restarts, would do, so
+// it is recorded next to the file when the file is created. A file without
+// one, empty or written by a previous version, is counted as created now.
+func (f *RotatingFile) creation() time.Time {
+	if f.size > 0 {
+		data, err := ioutil.ReadFile(f.createdPath())
+		if err == nil {
+			created, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(string(data)))
+			if err == nil {
+				return created
+			}
+		}
+	}
+
+	// The logs mustn't fail for want of the record, the age of the file is
+	// then counted from the next opening.
+	var now = time.Now()
+	_ = ioutil.WriteFile(f.createdPath(), []byte(now.UTC().Format(time.RFC3339Nano)+"\n"), 0644)
+	return now
+}
+
+// Write writes to the file, rotating it first if the write would make it too
+// big or if it is too old.
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file != nil && f.size > 0 && f.due(len(p)) {
+		err := f.rotate()
+		if err != nil && f.file == nil {
+			return 0, err
+		}
+	}
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Close closes the file, once the rotated files are compressed.
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	var err error
+	if f.file != nil {
+		err = f.file.Close()
+		f.file = nil
+	}
+	f.mu.Unlock()
+
+	f.done.Wait()
+	f.bg.Lock()
+	defer f.bg.Unlock()
+	if err == nil {
+		err = f.bgErr
+	}
+	return err
+}
+
+// due tells whether the file must be rotated before writing n bytes.
+func (f *RotatingFile) due(n int) bool {
+	if f.MaxSize > 0 && f.size+int64(n) > f.MaxSize {
+		return true
+	}
+	return f.MaxAge > 0 && time.Since(f.created) >= f.MaxAge
+}
+
+// rotate moves the file aside and opens a new one. The new file is opened
+// even if the previous one couldn't be moved, so the logs aren't lost. The
+// moved file is compressed in the background, so the writes don't wait for
+// it.
+func (f *RotatingFile) rotate() error {
+	var errs []error
+	errs = append(errs, f.file.Close())
+	f.file = nil
+
+	var rotated = f.path + "." + time.Now().UTC().Format(rotatedLayout)
+	err := os.Rename(f.path, rotated)
+	errs = append(errs, err)
+	if err != nil {
+		rotated = ""
+	}
+	if f.Compress {
+		f.enqueue(rotated)
+	} else {
+		errs = append(errs, f.prune())
+	}
+	errs = append(errs, f.open())
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// enqueue adds a rotated file to compress, the empty string to only prune
+// the rotated files, and starts the worker if it isn't running.
+func (f *RotatingFile) enqueue(path string) {
+	f.bg.Lock()
+	defer f.bg.Unlock()
+
+	f.queue = append(f.queue, path)
+	if !f.working {
+		f.working = true
+		f.done.Add(1)
+		go f.work()
+	}
+}
+
+// work compresses the queued files in order. The rotated files are pruned
+// once the queue is empty, with the queue locked, so a file waiting to be
+// compressed is never removed.
+func (f *RotatingFile) work() {
+	defer f.done.Done()
+
+	for {
+		f.bg.Lock()
+		if len(f.queue) == 0 {
+			f.fail(f.prune())
+			f.working = false
+			f.bg.Unlock()
+			return
+		}
+		var path = f.queue[0]
+		f.queue = f.queue[1:]
+		f.bg.Unlock()
+
+		if path == "" {
+			continue
+		}
+		err := gzipFile(path)
+		f.bg.Lock()
+		f.fail(err)
+		f.bg.Unlock()
+	}
+}
+
+// fail records the first error of the worker. It is called with bg locked.
+func (f *RotatingFile) fail(err error) {
+	if err != nil && f.bgErr == nil {
+		f.bgErr = err
+	}
+}
+
+// prune removes the oldest rotated files beyond the number of backups.
+func (f *RotatingFile) prune() error {
+	if f.Backups <= 0 {
+		return nil
+	}
+
+	matches, err := filepath.Glob(f.path + ".*")
+	if err != nil {
+		return err
+	}
+
+	var rotated []string
+	for _, m := range matches {
+		var suffix = strings.TrimSuffix(strings.TrimPrefix(m, f.path+"."), ".gz")
+		if _, err := time.Parse(rotatedLayout, suffix); err == nil {
+			rotated = append(rotated, m)
+		}
+	}
+	sort.Strings(rotated)
+
+	for len(rotated) > f.Backups {
+		err := os.Remove(rotated[0])
+		if err != nil {
+			return err
+		}
+		rotated = rotated[1:]
+	}
+	return nil
+}
+
+// gzipFile replaces a file by its gzipped version.
+func gzipFile(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(path + ".gz")
+	if err != nil {
+		return err
+	}
+
+	var zw = gzip.NewWriter(out)
+	_, err = io.Copy(zw, in)
+	if err == nil {
+		err = zw.Close()
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path + ".gz")
+		return err
+	}
+
+	return os.Remove(path)
+}
@@ -0,0 +1,300 @@
+package logger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRotateCompress rotates a compressed file several times, and checks that
+// every line is kept in the gzipped backups once the file is closed.
+func TestRotateCompress(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var path = filepath.Join(dir, "test.log")
+	file, err := OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.MaxSize = 1 << 10
+	file.Compress = true
+
+	var line = strings.Repeat("x", 99) + "\n"
+	for i := 0; i < 100; i++ {
+		_, err := file.Write([]byte(line))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = file.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	paths, err := filepath.Glob(path + ".*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) < 2 {
+		t.Fatalf("expected the file to be rotated, got %v", paths)
+	}
+
+	written, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range paths {
+		if !strings.HasSuffix(p, ".gz") {
+			t.Errorf("%s isn't compressed", p)
+			continue
+		}
+
+		in, err := os.Open(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		zr, err := gzip.NewReader(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadAll(zr)
+		in.Close()
+		if err != nil {
+			t.Fatalf("%s: %s", p, err)
+		}
+		written = append(written, data...)
+	}
+
+	if !bytes.Equal(written, bytes.Repeat([]byte(line), 100)) {
+		t.Errorf("expected 100 lines, got %d bytes", len(written))
+	}
+}
+
+// TestMaxAgeRestart reopens a file created longer ago than its maximum age,
+// but written just before, as by a program restarted several times, and
+// expects it to be rotated.
+func TestMaxAgeRestart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var path = filepath.Join(dir, "test.log")
+	file, err := OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.MaxAge = time.Hour
+	_, err = file.Write([]byte("old\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = file.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The file was created two hours ago, and last written now.
+	var created = filepath.Join(dir, ".test.log.created")
+	var old = time.Now().Add(-2 * time.Hour)
+	err = ioutil.WriteFile(created, []byte(old.Format(time.RFC3339Nano)), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, err = OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.MaxAge = time.Hour
+	for _, line := range []string{"new\n", "newer\n"} {
+		_, err = file.Write([]byte(line))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = file.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "new\nnewer\n" {
+		t.Errorf("expected the file to be rotated once, it holds %q", data)
+	}
+
+	// The creation of the new file is recorded.
+	data, err = ioutil.ReadFile(created)
+	if err != nil {
+		t.Fatal(err)
+	}
+	at, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(string(data)))
+	if err != nil || time.Since(at) > time.Minute {
+		t.Errorf("expected the creation of the new file to be recorded, got %q", data)
+	}
+}
+
+// TestMaxAgeUnknown opens a file without a record of its creation, which is
+// then counted as created now.
+func TestMaxAgeUnknown(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var path = filepath.Join(dir, "test.log")
+	err = ioutil.WriteFile(path, []byte("old\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.MaxAge = time.Hour
+	_, err = file.Write([]byte("new\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = file.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "old\nnew\n" {
+		t.Errorf("expected the file not to be rotated, it holds %q", data)
+	}
+}
+
+// TestCompressBackups rotates a compressed file many times with a single
+// backup: the backup must be the last rotated file, whole.
+func TestCompressBackups(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var path = filepath.Join(dir, "test.log")
+	file, err := OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.MaxSize = 100
+	file.Compress = true
+	file.Backups = 1
+
+	// Each line fills a file, the rotated file of line i holds line i-1.
+	const count = 50
+	for i := 0; i < count; i++ {
+		_, err := fmt.Fprintf(file, "%03d%s\n", i, strings.Repeat("x", 95))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = file.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	paths, err := filepath.Glob(path + ".*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 1 || !strings.HasSuffix(paths[0], ".gz") {
+		t.Fatalf("expected a single compressed backup, got %v", paths)
+	}
+
+	in, err := os.Open(paths[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+	zr, err := gzip.NewReader(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), fmt.Sprintf("%03d", count-2)) {
+		t.Errorf("expected the backup to hold the line %d, got %q", count-2, data)
+	}
+}
+
+// TestApplyClose checks that closing the logger configured by Apply waits for
+// the compression of the rotated files.
+func TestApplyClose(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var path = filepath.Join(dir, "test.log")
+	var fs = flag.NewFlagSet("test", flag.ContinueOnError)
+	var flags = RegisterFlags(fs)
+	err = fs.Parse([]string{"-silent", "-log-file", path, "-log-file-compress", "-log-file-max-size", "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var l = New(ioutil.Discard)
+	closeFile, err := flags.Apply(l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var padding = strings.Repeat("x", 64<<10)
+	for i := 0; i < 64; i++ {
+		l.Info("line", M{"i": i, "padding": padding})
+	}
+	err = closeFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	paths, err := filepath.Glob(path + ".*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) < 2 {
+		t.Fatalf("expected the file to be rotated, got %v", paths)
+	}
+	for _, p := range paths {
+		in, err := os.Open(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		zr, err := gzip.NewReader(in)
+		if err == nil {
+			_, err = io.Copy(ioutil.Discard, zr)
+		}
+		in.Close()
+		if err != nil {
+			t.Errorf("%s: %s", p, err)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package logger
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"time"
+)
+
+// Flags are the settings of a logger given on the command line.
+type Flags struct {
+	Silent bool
+	Level  string
+	Format string
+	Time   string
+	Caller bool
+
+	File         string
+	FileLevel    string
+	FileFormat   string
+	FileMaxSize  int
+	FileMaxAge   time.Duration
+	FileBackups  int
+	FileCompress bool
+}
+
+// RegisterFlags defines the flags of the settings of a logger in the given
+// flag set.
+func RegisterFlags(fs *flag.FlagSet) *Flags {
+	var f Flags
+	fs.BoolVar(&f.Silent, "silent", false, "suppress output")
+	fs.StringVar(&f.Level, "log-level", "info", "minimum level of the logged messages (debug, info, warn or error)")
+	fs.StringVar(&f.Format, "log-format", "logfmt", "format of the logged messages (logfmt or json)")
+	fs.StringVar(&f.Time, "log-time", "rfc3339", "format of the time of the logged messages (rfc3339, rfc3339nano, unixms or none)")
+	fs.BoolVar(&f.Caller, "log-caller", false, "add the file and line of the caller to the logged messages")
+	fs.StringVar(&f.File, "log-file", "", "file to also write the logged messages into")
+	fs.StringVar(&f.FileLevel, "log-file-level", "info", "minimum level of the messages logged into the file")
+	fs.StringVar(&f.FileFormat, "log-file-format", "json", "format of the messages logged into the file (logfmt or json)")
+	fs.IntVar(&f.FileMaxSize, "log-file-max-size", 100, "size in megabytes from which the log file is rotated, 0 for no limit")
+	fs.DurationVar(&f.FileMaxAge, "log-file-max-age", 0, "age from which the log file is rotated, 0 for no limit")
+	fs.IntVar(&f.FileBackups, "log-file-backups", 10, "number of rotated log files kept, 0 to keep them all")
+	fs.BoolVar(&f.FileCompress, "log-file-compress", false, "gzip the rotated log files")
+	return &f
+}
+
+// Apply configures the logger with the settings. The logger is left untouched
+// if any of them is invalid. The returned function closes the log file, if
+// any, once the rotated files are compressed: it must be called before the
+// program exits.
+func (f *Flags) Apply(l *Logger) (func() error, error) {
+	level, err := ParseLevel(f.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	enc, found := Encoders[f.Format]
+	if !found {
+		return nil, fmt.Errorf("unknown log format %q", f.Format)
+	}
+
+	layout, found := TimeFormats[f.Time]
+	if !found {
+		return nil, fmt.Errorf("unknown log time format %q", f.Time)
+	}
+
+	var sink Sink
+	var closeFile = func() error { return nil }
+	if f.File != "" {
+		sink.Level, err = ParseLevel(f.FileLevel)
+		if err != nil {
+			return nil, err
+		}
+
+		sink.Encoder, found = Encoders[f.FileFormat]
+		if !found {
+			return nil, fmt.Errorf("unknown log file format %q", f.FileFormat)
+		}
+
+		file, err := OpenFile(f.File)
+		if err != nil {
+			return nil, err
+		}
+		file.MaxSize = int64(f.FileMaxSize) << 20
+		file.MaxAge = f.FileMaxAge
+		file.Backups = f.FileBackups
+		file.Compress = f.FileCompress
+		sink.Writer = file
+		closeFile = file.Close
+	}
+
+	if f.Silent {
+		l.SetOutput(ioutil.Discard)
+	}
+	if sink.Writer != nil {
+		l.AddSink(sink)
+	}
+	l.SetLevel(level)
+	l.SetEncoder(enc)
+	l.SetTimeFormat(layout)
+	l.SetCaller(f.Caller)
+	return closeFile, nil
+}
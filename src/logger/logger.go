@@ -5,18 +5,103 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"sort"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
 	"time"
-
-	"github.com/go-logfmt/logfmt"
 )
 
 type M map[string]interface{}
 
-// A Logger is a simple structured logger implementation using logfmt format.
+// A Level is the severity of a log line.
+type Level int
+
+// Levels, from the least to the most severe.
+const (
+	DebugLevel Level = iota
+	InfoLevel
+	WarnLevel
+	ErrorLevel
+)
+
+var levels = map[Level]string{
+	DebugLevel: "debug",
+	InfoLevel:  "info",
+	WarnLevel:  "warn",
+	ErrorLevel: "error",
+}
+
+// String returns the name of the level.
+func (lvl Level) String() string {
+	if name, found := levels[lvl]; found {
+		return name
+	}
+	return fmt.Sprintf("level(%d)", int(lvl))
+}
+
+// ParseLevel returns the level of the given name.
+func ParseLevel(name string) (Level, error) {
+	for lvl, n := range levels {
+		if strings.EqualFold(name, n) {
+			return lvl, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown log level %q", name)
+}
+
+// A Logger is a simple structured logger implementation, writing logfmt by
+// default. It is safe for concurrent use: each line is written at once, and
+// the loggers derived with With share the output and sinks of their parent.
 type Logger struct {
-	out io.Writer
+	out *output
 	ctx M
+
+	mu       sync.RWMutex
+	settings settings
+}
+
+// settings are the per-logger settings, inherited by the derived loggers.
+type settings struct {
+	level  Level
+	enc    Encoder
+	clock  func() time.Time
+	layout string
+	caller bool
+}
+
+// A Sink is an additional destination of the lines of a logger, with its own
+// format and minimum level.
+type Sink struct {
+	Writer  io.Writer
+	Encoder Encoder
+	Level   Level
+}
+
+// output serializes the writes of a family of loggers.
+type output struct {
+	mu    sync.Mutex
+	w     io.Writer
+	sinks []Sink
+}
+
+// line is a part of a buffer to write on a writer, the output of the logger
+// if none.
+type line struct {
+	w          io.Writer
+	start, end int
+}
+
+func (o *output) write(buf []byte, lines []line) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, l := range lines {
+		var w = l.w
+		if w == nil {
+			w = o.w
+		}
+		_, _ = w.Write(buf[l.start:l.end])
+	}
 }
 
 // New returns a new logger which will write to the given writer.
@@ -26,7 +111,13 @@ func New(out io.Writer) *Logger {
 	}
 
 	return &Logger{
-		out: out,
+		out: &output{w: out},
+		settings: settings{
+			level:  InfoLevel,
+			enc:    LogfmtEncoder{},
+			clock:  time.Now,
+			layout: time.RFC3339,
+		},
 	}
 }
 
@@ -45,63 +136,206 @@ func (l *Logger) With(ctx M) *Logger {
 		}
 	}
 
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
 	// Return a new logger with the new context.
 	return &Logger{
-		out: l.out,
-		ctx: c,
+		out:      l.out,
+		ctx:      c,
+		settings: l.settings,
 	}
 }
 
 // Log writes a log line to the output of the logger with the level, message
-// and data in parameters.
-func (l *Logger) Log(lvl, msg string, data M) {
-	// Ensure the map is initialized.
-	if data == nil {
-		data = M{}
+// and data in parameters. Lines below the minimum level of the logger are
+// dropped.
+func (l *Logger) Log(lvl Level, msg string, data M) {
+	l.Output(2, lvl, msg, data)
+}
+
+// Output writes a log line like Log. The caller reported, if enabled, is the
+// one depth frames up the stack, 1 being the caller of Output. Helpers
+// logging on behalf of their callers use it to report their callers instead.
+func (l *Logger) Output(depth int, lvl Level, msg string, data M) {
+	l.mu.RLock()
+	var s = l.settings
+	l.mu.RUnlock()
+
+	l.out.mu.Lock()
+	var sinks = l.out.sinks
+	l.out.mu.Unlock()
+
+	var wanted = lvl >= s.level
+	for _, sink := range sinks {
+		wanted = wanted || lvl >= sink.Level
+	}
+	if !wanted {
+		return
 	}
 
-	// Add the fields from the context.
-	if l.ctx != nil {
-		for k, v := range l.ctx {
-			data[k] = v
+	// Copy the fields into a new map, so the given one is left untouched. The
+	// fields of the call take precedence over the ones of the context.
+	var fields = make(M, len(l.ctx)+len(data)+4)
+	copyFields(fields, l.ctx)
+	copyFields(fields, data)
+
+	// Add the per-line fields.
+	fields["lvl"] = lvl.String()
+	fields["msg"] = msg
+	switch s.layout {
+	case NoTime:
+	case UnixMillis:
+		fields["time"] = s.clock().UnixNano() / int64(time.Millisecond)
+	default:
+		fields["time"] = s.clock().Format(s.layout)
+	}
+	if s.caller {
+		if _, file, line, ok := runtime.Caller(depth); ok {
+			fields["caller"] = fmt.Sprintf("%s/%s:%d", filepath.Base(filepath.Dir(file)), filepath.Base(file), line)
 		}
 	}
 
-	// Add the par-line fields.
-	data["lvl"] = lvl
-	data["msg"] = msg
-	data["time"] = time.Now().Format(time.RFC3339)
+	// Encode them on the buffer, once for the output and once for each sink
+	// wanting the line.
+	var buf = buffers.Get().(*bytes.Buffer)
+	defer buffers.Put(buf)
+	buf.Reset()
 
-	// Get each resulting field and sort them.
-	fields := make([]string, 0, len(data))
-	for f := range data {
-		fields = append(fields, f)
+	var lines = make([]line, 0, 1+len(sinks))
+	var encode = func(w io.Writer, enc Encoder) {
+		var start = buf.Len()
+		if enc.Encode(buf, fields) != nil {
+			buf.Truncate(start)
+			return
+		}
+		lines = append(lines, line{w: w, start: start, end: buf.Len()})
+	}
+	if lvl >= s.level {
+		encode(nil, s.enc)
+	}
+	for _, sink := range sinks {
+		if lvl >= sink.Level {
+			encode(sink.Writer, sink.Encoder)
+		}
 	}
-	sort.Strings(fields)
 
-	// Write them on the buffer.
-	var buf bytes.Buffer
-	var enc = logfmt.NewEncoder(&buf)
-	for _, f := range fields {
-		_ = enc.EncodeKeyval(f, fmt.Sprint(data[f]))
+	// Write the lines, in one go.
+	l.out.write(buf.Bytes(), lines)
+}
+
+// reserved are the keys of the per-line fields. Fields of the same name given
+// by the caller or the context are kept under the "fields." prefix.
+var reserved = map[string]bool{
+	"lvl":    true,
+	"msg":    true,
+	"time":   true,
+	"caller": true,
+}
+
+func copyFields(dst, src M) {
+	for k, v := range src {
+		if reserved[k] {
+			k = "fields." + k
+		}
+		dst[k] = v
 	}
-	_ = enc.EndRecord()
+}
 
-	// Write the buffer on the output.
-	_, _ = l.out.Write(buf.Bytes())
+// buffers are reused between lines to spare allocations.
+var buffers = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
 }
 
-// Error is a shortcut to write an error log line.
-func (l *Logger) Error(msg string, data M) {
-	l.Log("error", msg, data)
+// Debug is a shortcut to write a debug log line.
+func (l *Logger) Debug(msg string, data M) {
+	l.Output(2, DebugLevel, msg, data)
 }
 
 // Info is a shortcut to write an info log line.
 func (l *Logger) Info(msg string, data M) {
-	l.Log("info", msg, data)
+	l.Output(2, InfoLevel, msg, data)
+}
+
+// Warn is a shortcut to write a warning log line.
+func (l *Logger) Warn(msg string, data M) {
+	l.Output(2, WarnLevel, msg, data)
+}
+
+// Error is a shortcut to write an error log line.
+func (l *Logger) Error(msg string, data M) {
+	l.Output(2, ErrorLevel, msg, data)
+}
+
+// SetLevel sets the minimum level of the lines written by the logger.
+func (l *Logger) SetLevel(lvl Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.settings.level = lvl
+}
+
+// SetEncoder sets the format of the lines written by the logger.
+func (l *Logger) SetEncoder(enc Encoder) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.settings.enc = enc
+}
+
+// Time formats which aren't layouts of the time package.
+const (
+	// NoTime removes the time from the lines.
+	NoTime = ""
+	// UnixMillis writes the time as a number of milliseconds since the epoch.
+	UnixMillis = "unixms"
+)
+
+// TimeFormats are the available time formats, by name.
+var TimeFormats = map[string]string{
+	"rfc3339":     time.RFC3339,
+	"rfc3339nano": time.RFC3339Nano,
+	"unixms":      UnixMillis,
+	"none":        NoTime,
+}
+
+// SetTimeFormat sets the format of the time of the lines: a layout of the
+// time package, UnixMillis, or NoTime.
+func (l *Logger) SetTimeFormat(layout string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.settings.layout = layout
+}
+
+// SetClock sets the function giving the time of the lines, time.Now by
+// default.
+func (l *Logger) SetClock(clock func() time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.settings.clock = clock
+}
+
+// SetCaller sets whether the file and line of the caller are added to the
+// lines.
+func (l *Logger) SetCaller(caller bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.settings.caller = caller
+}
+
+// AddSink adds a destination to the lines of the logger, and of the loggers
+// sharing its output.
+func (l *Logger) AddSink(sink Sink) {
+	l.out.mu.Lock()
+	defer l.out.mu.Unlock()
+	// Copy the sinks, the previous ones may be in use.
+	l.out.sinks = append(l.out.sinks[:len(l.out.sinks):len(l.out.sinks)], sink)
 }
 
-// SetOutput allows to change the output of the logger.
+// SetOutput allows to change the output of the logger, and of the loggers
+// sharing it. The lines being written are finished on the previous output.
 func (l *Logger) SetOutput(out io.Writer) {
-	l.out = out
+	l.out.mu.Lock()
+	defer l.out.mu.Unlock()
+	l.out.w = out
 }
@@ -0,0 +1,348 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// readLines decodes the JSON lines of the given data, failing on any line
+// which isn't a whole line, as an interleaved one wouldn't be.
+func readLines(t *testing.T, data []byte) []M {
+	var lines []M
+	var scanner = bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var fields M
+		err := json.Unmarshal(scanner.Bytes(), &fields)
+		if err != nil {
+			t.Fatalf("malformed line %q: %s", scanner.Text(), err)
+		}
+		lines = append(lines, fields)
+	}
+	return lines
+}
+
+// TestConcurrentLog writes from several goroutines through children of a
+// logger, into a rotating sink, while the output of the logger is changed.
+// Run it with -race.
+func TestConcurrentLog(t *testing.T) {
+	const (
+		goroutines = 8
+		count      = 200
+	)
+
+	dir, err := ioutil.TempDir("", "logger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var path = filepath.Join(dir, "test.log")
+	file, err := OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.MaxSize = 16 << 10
+
+	var outputs = []*bytes.Buffer{new(bytes.Buffer), new(bytes.Buffer)}
+	var l = New(outputs[0])
+	l.SetEncoder(JSONEncoder{})
+	l.AddSink(Sink{
+		Writer:  file,
+		Encoder: JSONEncoder{},
+		Level:   DebugLevel,
+	})
+
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			var child = l.With(M{"goroutine": g})
+			for i := 0; i < count; i++ {
+				child.Info("line", M{
+					"i":       i,
+					"padding": strings.Repeat("x", 100),
+				})
+			}
+		}(g)
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			l.SetOutput(outputs[i%2])
+		}
+	}()
+
+	wg.Wait()
+	err = file.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var check = func(name string, lines []M) {
+		var seen = make(map[string]bool)
+		for _, fields := range lines {
+			var key = fmt.Sprintf("%v/%v", fields["goroutine"], fields["i"])
+			if seen[key] {
+				t.Errorf("%s: line %s written twice", name, key)
+			}
+			seen[key] = true
+		}
+		if len(seen) != goroutines*count {
+			t.Errorf("%s: expected %d lines, got %d", name, goroutines*count, len(seen))
+		}
+	}
+
+	var written []M
+	for _, out := range outputs {
+		written = append(written, readLines(t, out.Bytes())...)
+	}
+	check("outputs", written)
+
+	paths, err := filepath.Glob(path + "*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) < 2 {
+		t.Errorf("expected the file to be rotated, got %v", paths)
+	}
+	var logged []M
+	for _, p := range paths {
+		data, err := ioutil.ReadFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		logged = append(logged, readLines(t, data)...)
+	}
+	check("file", logged)
+}
+
+func TestFields(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		ctx      M
+		data     M
+		expected M
+	}{
+		{
+			name:     "context",
+			ctx:      M{"app": "wtc"},
+			expected: M{"app": "wtc"},
+		},
+		{
+			name:     "call",
+			data:     M{"round": 1.0},
+			expected: M{"round": 1.0},
+		},
+		{
+			name:     "call over context",
+			ctx:      M{"app": "wtc", "cmd": "crawl"},
+			data:     M{"cmd": "load"},
+			expected: M{"app": "wtc", "cmd": "load"},
+		},
+		{
+			name:     "reserved call keys",
+			data:     M{"msg": "theirs", "lvl": "theirs", "time": "theirs", "caller": "theirs"},
+			expected: M{"fields.msg": "theirs", "fields.lvl": "theirs", "fields.time": "theirs", "fields.caller": "theirs"},
+		},
+		{
+			name:     "reserved context keys",
+			ctx:      M{"msg": "context"},
+			data:     M{"lvl": "call"},
+			expected: M{"fields.msg": "context", "fields.lvl": "call"},
+		},
+		{
+			name:     "reserved keys in both",
+			ctx:      M{"msg": "context"},
+			data:     M{"msg": "call"},
+			expected: M{"fields.msg": "call"},
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			var out bytes.Buffer
+			var l = New(&out)
+			l.SetEncoder(JSONEncoder{})
+			l.SetTimeFormat(NoTime)
+			if c.ctx != nil {
+				l = l.With(c.ctx)
+			}
+
+			l.Info("message", c.data)
+
+			var lines = readLines(t, out.Bytes())
+			if len(lines) != 1 {
+				t.Fatalf("expected 1 line, got %d", len(lines))
+			}
+			var expected = M{"lvl": "info", "msg": "message"}
+			for k, v := range c.expected {
+				expected[k] = v
+			}
+			if fmt.Sprint(lines[0]) != fmt.Sprint(expected) {
+				t.Errorf("expected %v, got %v", expected, lines[0])
+			}
+		})
+	}
+}
+
+func TestTimeFormats(t *testing.T) {
+	var clock = func() time.Time {
+		return time.Date(2026, 10, 19, 7, 4, 5, 123456789, time.FixedZone("CEST", 2*60*60))
+	}
+
+	for _, c := range []struct {
+		format   string
+		expected string
+	}{
+		{"rfc3339", "lvl=info msg=message time=2026-10-19T07:04:05+02:00\n"},
+		{"rfc3339nano", "lvl=info msg=message time=2026-10-19T07:04:05.123456789+02:00\n"},
+		{"unixms", "lvl=info msg=message time=1792386245123\n"},
+		{"none", "lvl=info msg=message\n"},
+	} {
+		t.Run(c.format, func(t *testing.T) {
+			var out bytes.Buffer
+			var l = New(&out)
+			l.SetClock(clock)
+			l.SetTimeFormat(TimeFormats[c.format])
+
+			l.Info("message", nil)
+			if out.String() != c.expected {
+				t.Errorf("expected %q, got %q", c.expected, out.String())
+			}
+		})
+	}
+
+	// The time keeps its type in JSON.
+	var out bytes.Buffer
+	var l = New(&out)
+	l.SetEncoder(JSONEncoder{})
+	l.SetClock(clock)
+	l.SetTimeFormat(UnixMillis)
+	l.Info("message", nil)
+	var expected = `{"lvl":"info","msg":"message","time":1792386245123}` + "\n"
+	if out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+}
+
+// logHelper logs on behalf of its caller.
+func logHelper(l *Logger, msg string) {
+	l.Output(2, InfoLevel, msg, nil)
+}
+
+func TestCaller(t *testing.T) {
+	var out bytes.Buffer
+	var l = New(&out)
+	l.SetTimeFormat(NoTime)
+	l.SetCaller(true)
+
+	var _, _, line, _ = runtime.Caller(0)
+	l.Info("direct", nil)
+	logHelper(l, "helper")
+	l.With(M{"app": "wtc"}).Log(WarnLevel, "child", nil)
+
+	var expected = fmt.Sprintf(
+		"caller=logger/logger_test.go:%d lvl=info msg=direct\n"+
+			"caller=logger/logger_test.go:%d lvl=info msg=helper\n"+
+			"app=wtc caller=logger/logger_test.go:%d lvl=warn msg=child\n",
+		line+1, line+2, line+3,
+	)
+	if out.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, out.String())
+	}
+
+	// Without it, no caller is added.
+	out.Reset()
+	l.SetCaller(false)
+	l.Info("direct", nil)
+	if out.String() != "lvl=info msg=direct\n" {
+		t.Errorf("unexpected line %q", out.String())
+	}
+}
+
+func TestLogDoesNotMutate(t *testing.T) {
+	var out bytes.Buffer
+	var l = New(&out).With(M{"app": "wtc"})
+	l.SetEncoder(JSONEncoder{})
+
+	var data = M{"round": 1, "msg": "theirs"}
+	l.Info("first", data)
+	if len(data) != 2 || data["round"] != 1 || data["msg"] != "theirs" {
+		t.Errorf("the fields of the call were modified: %v", data)
+	}
+
+	// The fields of a call mustn't leak into the context either.
+	l.Info("second", nil)
+	var lines = readLines(t, out.Bytes())
+	if _, found := lines[1]["round"]; found {
+		t.Errorf("the fields of a call leaked into the next line: %v", lines[1])
+	}
+}
+
+// logMutating is the implementation of Log before the fields were copied: it
+// adds the context and per-line fields into the map of the caller, and
+// allocates a buffer per line. It is the baseline of BenchmarkLog.
+func logMutating(l *Logger, lvl Level, msg string, data M) {
+	l.mu.RLock()
+	var level, enc = l.settings.level, l.settings.enc
+	l.mu.RUnlock()
+
+	if lvl < level {
+		return
+	}
+
+	if data == nil {
+		data = M{}
+	}
+	for k, v := range l.ctx {
+		data[k] = v
+	}
+	data["lvl"] = lvl.String()
+	data["msg"] = msg
+	data["time"] = l.settings.clock().Format(time.RFC3339)
+
+	var buf bytes.Buffer
+	err := enc.Encode(&buf, data)
+	if err != nil {
+		return
+	}
+	l.out.write(buf.Bytes(), []line{{start: 0, end: buf.Len()}})
+}
+
+func BenchmarkLog(b *testing.B) {
+	var l = New(ioutil.Discard).With(M{
+		"app": "wtc",
+		"cmd": "load",
+	})
+
+	for _, bench := range []struct {
+		name string
+		log  func(msg string, data M)
+	}{
+		{"mutating", func(msg string, data M) { logMutating(l, InfoLevel, msg, data) }},
+		{"copying", func(msg string, data M) { l.Info(msg, data) }},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				bench.log("inserting match", M{
+					"round": 3,
+					"zone":  "12",
+					"err":   os.ErrNotExist,
+				})
+			}
+		})
+	}
+}
@@ -0,0 +1,299 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the upper bounds in seconds of the buckets of a
+// histogram of durations, from 5ms to 10s.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// A Registry holds metrics and exposes them in the Prometheus text format.
+// It is safe for concurrent use.
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+}
+
+type metric interface {
+	write(w *bufio.Writer)
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Counter registers a counter of the given name, whose series are identified
+// by the values of the given labels.
+func (r *Registry) Counter(name, help string, labels ...string) *Counter {
+	var c = &Counter{
+		family: newFamily(name, help, labels),
+	}
+	r.register(c)
+	return c
+}
+
+// Histogram registers a histogram of the given name with the given bucket
+// upper bounds, whose series are identified by the values of the given
+// labels.
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	var bounds = append([]float64(nil), buckets...)
+	sort.Float64s(bounds)
+	var h = &Histogram{
+		family:  newFamily(name, help, labels),
+		buckets: bounds,
+	}
+	r.register(h)
+	return h
+}
+
+func (r *Registry) register(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.metrics = append(r.metrics, m)
+}
+
+// WriteTo writes the metrics in the Prometheus text format.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	var metrics = append([]metric(nil), r.metrics...)
+	r.mu.Unlock()
+
+	var cw = &countingWriter{w: w}
+	var bw = bufio.NewWriter(cw)
+	for _, m := range metrics {
+		m.write(bw)
+	}
+	err := bw.Flush()
+	return cw.n, err
+}
+
+// Listen serves the metrics on /metrics at the given address in the
+// background, once the address is bound.
+func (r *Registry) Listen(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	var mux = http.NewServeMux()
+	mux.Handle("/metrics", r)
+	go http.Serve(listener, mux)
+	return nil
+}
+
+// ServeHTTP serves the metrics in the Prometheus text format.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = r.WriteTo(w)
+}
+
+// A family is the set of series of a metric, one per combination of label
+// values.
+type family struct {
+	name   string
+	help   string
+	labels []string
+	mu     sync.Mutex
+	keys   []string
+	values map[string][]string
+}
+
+func newFamily(name, help string, labels []string) family {
+	return family{
+		name:   name,
+		help:   help,
+		labels: labels,
+		values: make(map[string][]string),
+	}
+}
+
+// key returns the key of the series of the given label values, registering
+// the series on first use. The family must be locked.
+func (f *family) key(values []string) (string, bool) {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s has %d labels, got %d values", f.name, len(f.labels), len(values)))
+	}
+
+	var key = strings.Join(values, "\xff")
+	if _, found := f.values[key]; found {
+		return key, false
+	}
+	f.keys = append(f.keys, key)
+	f.values[key] = append([]string(nil), values...)
+	return key, true
+}
+
+func (f *family) header(w *bufio.Writer, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", f.name, kind)
+}
+
+// sorted returns the keys of the series in the order of their label values.
+// The family must be locked.
+func (f *family) sorted() []string {
+	var keys = append([]string(nil), f.keys...)
+	sort.Strings(keys)
+	return keys
+}
+
+// series writes a sample of the series of the given key, with an additional
+// label if extra isn't empty.
+func (f *family) series(w *bufio.Writer, suffix, key string, extra []string, value float64) {
+	var pairs []string
+	for i, label := range f.labels {
+		pairs = append(pairs, label+`="`+escapeValue(f.values[key][i])+`"`)
+	}
+	if len(extra) == 2 {
+		pairs = append(pairs, extra[0]+`="`+escapeValue(extra[1])+`"`)
+	}
+
+	w.WriteString(f.name + suffix)
+	if len(pairs) != 0 {
+		w.WriteString("{" + strings.Join(pairs, ",") + "}")
+	}
+	w.WriteString(" " + formatFloat(value) + "\n")
+}
+
+// A Counter is a metric which only goes up: a number of requests or errors,
+// for example.
+type Counter struct {
+	family
+	counts map[string]float64
+}
+
+// Inc adds one to the series of the given label values.
+func (c *Counter) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+// Add adds n to the series of the given label values. Negative values are
+// ignored.
+func (c *Counter) Add(n float64, values ...string) {
+	if n < 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.counts == nil {
+		c.counts = make(map[string]float64)
+	}
+	key, _ := c.key(values)
+	c.counts[key] += n
+}
+
+func (c *Counter) write(w *bufio.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.header(w, "counter")
+	for _, key := range c.sorted() {
+		c.series(w, "", key, nil, c.counts[key])
+	}
+}
+
+// A Histogram is a metric counting observations in buckets: the durations of
+// requests, for example.
+type Histogram struct {
+	family
+	buckets []float64
+	samples map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// Observe adds an observation to the series of the given label values.
+func (h *Histogram) Observe(v float64, values ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.samples == nil {
+		h.samples = make(map[string]*histogramSeries)
+	}
+	key, created := h.key(values)
+	if created {
+		h.samples[key] = &histogramSeries{
+			counts: make([]uint64, len(h.buckets)),
+		}
+	}
+
+	var s = h.samples[key]
+	for i, bound := range h.buckets {
+		if v <= bound {
+			s.counts[i]++
+			break
+		}
+	}
+	s.count++
+	s.sum += v
+}
+
+func (h *Histogram) write(w *bufio.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.header(w, "histogram")
+	for _, key := range h.sorted() {
+		var s = h.samples[key]
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += s.counts[i]
+			h.series(w, "_bucket", key, []string{"le", formatFloat(bound)}, float64(cumulative))
+		}
+		h.series(w, "_bucket", key, []string{"le", "+Inf"}, float64(s.count))
+		h.series(w, "_sum", key, nil, s.sum)
+		h.series(w, "_count", key, nil, float64(s.count))
+	}
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	valueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func escapeValue(s string) string {
+	return valueEscaper.Replace(s)
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,130 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+)
+
+// expose returns the text exposition of the registry.
+func expose(t *testing.T, r *Registry) string {
+	var buf bytes.Buffer
+	n, err := r.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("expected %d bytes to be counted, got %d", buf.Len(), n)
+	}
+	return buf.String()
+}
+
+func TestCounter(t *testing.T) {
+	var r = NewRegistry()
+	var total = r.Counter("wtc_matches_total", "Number of matches.")
+	var requests = r.Counter("wtc_requests_total", "Number of requests, by status.", "status")
+	total.Inc()
+	total.Add(2.5)
+	total.Add(-1)
+	requests.Inc("500")
+	requests.Add(3, "200")
+	requests.Inc("500")
+
+	var expected = `# HELP wtc_matches_total Number of matches.
+# TYPE wtc_matches_total counter
+wtc_matches_total 3.5
+# HELP wtc_requests_total Number of requests, by status.
+# TYPE wtc_requests_total counter
+wtc_requests_total{status="200"} 3
+wtc_requests_total{status="500"} 2
+`
+	if out := expose(t, r); out != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, out)
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	var r = NewRegistry()
+	var h = r.Histogram("wtc_duration_seconds", "Duration.", []float64{1, 0.5, 2}, "stage")
+	for _, v := range []float64{0.25, 0.5, 1.5, 3} {
+		h.Observe(v, "load")
+	}
+	h.Observe(0.75, "crawl")
+
+	var expected = `# HELP wtc_duration_seconds Duration.
+# TYPE wtc_duration_seconds histogram
+wtc_duration_seconds_bucket{stage="crawl",le="0.5"} 0
+wtc_duration_seconds_bucket{stage="crawl",le="1"} 1
+wtc_duration_seconds_bucket{stage="crawl",le="2"} 1
+wtc_duration_seconds_bucket{stage="crawl",le="+Inf"} 1
+wtc_duration_seconds_sum{stage="crawl"} 0.75
+wtc_duration_seconds_count{stage="crawl"} 1
+wtc_duration_seconds_bucket{stage="load",le="0.5"} 2
+wtc_duration_seconds_bucket{stage="load",le="1"} 2
+wtc_duration_seconds_bucket{stage="load",le="2"} 3
+wtc_duration_seconds_bucket{stage="load",le="+Inf"} 4
+wtc_duration_seconds_sum{stage="load"} 5.25
+wtc_duration_seconds_count{stage="load"} 4
+`
+	if out := expose(t, r); out != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, out)
+	}
+}
+
+func TestHistogramWithoutLabels(t *testing.T) {
+	var r = NewRegistry()
+	var h = r.Histogram("wtc_insert_seconds", "Insertions.", []float64{0.01, 0.1})
+	h.Observe(0.0078125)
+	h.Observe(0.0625)
+
+	var expected = `# HELP wtc_insert_seconds Insertions.
+# TYPE wtc_insert_seconds histogram
+wtc_insert_seconds_bucket{le="0.01"} 1
+wtc_insert_seconds_bucket{le="0.1"} 2
+wtc_insert_seconds_bucket{le="+Inf"} 2
+wtc_insert_seconds_sum 0.0703125
+wtc_insert_seconds_count 2
+`
+	if out := expose(t, r); out != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, out)
+	}
+}
+
+func TestEscaping(t *testing.T) {
+	var r = NewRegistry()
+	var c = r.Counter("wtc_errors_total", "Errors by \"kind\",\nwith a \\ backslash.", "kind", "path")
+	c.Inc("say \"hi\"", `C:\logs`+"\n")
+
+	var expected = `# HELP wtc_errors_total Errors by "kind",\nwith a \\ backslash.
+# TYPE wtc_errors_total counter
+wtc_errors_total{kind="say \"hi\"",path="C:\\logs\n"} 1
+`
+	if out := expose(t, r); out != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, out)
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	var r = NewRegistry()
+	r.Counter("wtc_total", "Total.").Inc()
+
+	var w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+	if ct := w.Header().Get("Content-Type"); ct != "text/plain; version=0.0.4; charset=utf-8" {
+		t.Errorf("unexpected content type %q", ct)
+	}
+	var expected = "# HELP wtc_total Total.\n# TYPE wtc_total counter\nwtc_total 1\n"
+	if w.Body.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, w.Body.String())
+	}
+}
+
+func TestLabelCount(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic on a wrong number of label values")
+		}
+	}()
+	NewRegistry().Counter("wtc_total", "Total.", "status").Inc()
+}
@@ -0,0 +1,136 @@
+package progress
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// barWidth is the number of characters of the bar itself.
+const barWidth = 20
+
+// A Bar draws the progress of a run on the last line of a terminal. The lines
+// written through the bar, the logs for example, are written above it.
+type Bar struct {
+	// Extra returns a text appended to the bar, the number of errors of the
+	// run for example.
+	Extra func() string
+
+	tracker *Tracker
+	out     io.Writer
+	mu      sync.Mutex
+	drawn   bool
+	stopped bool
+	done    chan struct{}
+}
+
+// IsTerminal tells whether the file is a terminal.
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// NewBar returns a bar drawing the progress of the tracker on the given
+// output.
+func NewBar(tracker *Tracker, out io.Writer) *Bar {
+	return &Bar{
+		tracker: tracker,
+		out:     out,
+		done:    make(chan struct{}),
+	}
+}
+
+// Start draws the bar at the given interval, until the bar is stopped.
+func (b *Bar) Start(interval time.Duration) {
+	go func() {
+		var ticker = time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				b.mu.Lock()
+				b.draw()
+				b.mu.Unlock()
+			case <-b.done:
+				return
+			}
+		}
+	}()
+}
+
+// Stop draws the bar a last time and leaves it above the following lines.
+func (b *Bar) Stop() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.stopped {
+		return
+	}
+	close(b.done)
+	b.draw()
+	fmt.Fprintln(b.out)
+	b.stopped = true
+	b.drawn = false
+}
+
+// Write writes the given lines above the bar.
+func (b *Bar) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.stopped {
+		return b.out.Write(p)
+	}
+
+	b.clear()
+	n, err := b.out.Write(p)
+	b.draw()
+	return n, err
+}
+
+func (b *Bar) clear() {
+	if b.drawn {
+		fmt.Fprint(b.out, "\r\033[K")
+		b.drawn = false
+	}
+}
+
+func (b *Bar) draw() {
+	if b.stopped {
+		return
+	}
+
+	var s = b.tracker.Snapshot()
+	var parts []string
+	for _, c := range s.Counters {
+		if c.Total > 0 {
+			var filled = int(barWidth * c.Count / c.Total)
+			if filled > barWidth {
+				filled = barWidth
+			}
+			parts = append(parts, fmt.Sprintf("[%s%s] %s %d/%d", strings.Repeat("#", filled), strings.Repeat("-", barWidth-filled), c.Name, c.Count, c.Total))
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s %d (%.1f/s)", c.Name, c.Count, c.Rate))
+	}
+	if b.Extra != nil {
+		if extra := b.Extra(); extra != "" {
+			parts = append(parts, extra)
+		}
+	}
+	var elapsed = time.Duration(s.Seconds * float64(time.Second))
+	parts = append(parts, elapsed.Round(100*time.Millisecond).String())
+
+	var line bytes.Buffer
+	line.WriteString("\r\033[K")
+	line.WriteString(strings.Join(parts, "  "))
+	_, _ = b.out.Write(line.Bytes())
+	b.drawn = true
+}
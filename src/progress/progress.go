@@ -0,0 +1,144 @@
+package progress
+
+import (
+	"sync"
+	"time"
+)
+
+// A Tracker counts the events of a run by name, pages retrieved or games
+// inserted for example, and times the stages of the run. It is safe for
+// concurrent use.
+type Tracker struct {
+	mu      sync.Mutex
+	started time.Time
+	names   []string
+	counts  map[string]int64
+	totals  map[string]int64
+	stages  []string
+	running map[string]time.Time
+	elapsed map[string]time.Duration
+}
+
+// New returns a tracker, the run starting now.
+func New() *Tracker {
+	return &Tracker{
+		started: time.Now(),
+		counts:  make(map[string]int64),
+		totals:  make(map[string]int64),
+		running: make(map[string]time.Time),
+		elapsed: make(map[string]time.Duration),
+	}
+}
+
+// Add counts n events of the given name.
+func (t *Tracker) Add(name string, n int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.register(name)
+	t.counts[name] += int64(n)
+}
+
+// Expect adds n to the number of events of the given name expected by the
+// end of the run.
+func (t *Tracker) Expect(name string, n int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.register(name)
+	t.totals[name] += int64(n)
+}
+
+func (t *Tracker) register(name string) {
+	if _, found := t.counts[name]; !found {
+		t.names = append(t.names, name)
+		t.counts[name] = 0
+	}
+}
+
+// Start starts the timing of a stage, and returns the function stopping it.
+// A stage run several times is timed in total.
+func (t *Tracker) Start(stage string) func() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, found := t.elapsed[stage]; !found {
+		t.stages = append(t.stages, stage)
+		t.elapsed[stage] = 0
+	}
+	t.running[stage] = time.Now()
+
+	return func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+
+		if start, found := t.running[stage]; found {
+			t.elapsed[stage] += time.Since(start)
+			delete(t.running, stage)
+		}
+	}
+}
+
+// A Counter is the state of the events of a name.
+type Counter struct {
+	Name  string  `json:"name"`
+	Count int64   `json:"count"`
+	Total int64   `json:"total,omitempty"`
+	Rate  float64 `json:"rate"`
+}
+
+// A Stage is the time spent in a stage.
+type Stage struct {
+	Name    string  `json:"name"`
+	Seconds float64 `json:"seconds"`
+}
+
+// A Snapshot is the state of a run at a given time.
+type Snapshot struct {
+	Started  time.Time `json:"started"`
+	Seconds  float64   `json:"seconds"`
+	Counters []Counter `json:"counters"`
+	Stages   []Stage   `json:"stages"`
+}
+
+// Snapshot returns the current state of the run. The rates are in events per
+// second since the start of the run, and the running stages are timed up to
+// now.
+func (t *Tracker) Snapshot() Snapshot {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var now = time.Now()
+	var elapsed = now.Sub(t.started)
+	var s = Snapshot{
+		Started:  t.started,
+		Seconds:  elapsed.Seconds(),
+		Counters: make([]Counter, 0, len(t.names)),
+		Stages:   make([]Stage, 0, len(t.stages)),
+	}
+
+	for _, name := range t.names {
+		var c = Counter{
+			Name:  name,
+			Count: t.counts[name],
+			Total: t.totals[name],
+		}
+		if elapsed > 0 {
+			c.Rate = float64(c.Count) / elapsed.Seconds()
+		}
+		s.Counters = append(s.Counters, c)
+	}
+
+	for _, name := range t.stages {
+		var d = t.elapsed[name]
+		if start, found := t.running[name]; found {
+			d += now.Sub(start)
+		}
+		s.Stages = append(s.Stages, Stage{
+			Name:    name,
+			Seconds: d.Seconds(),
+		})
+	}
+
+	return s
+}
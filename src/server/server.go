@@ -0,0 +1,401 @@
+// Package server serves the tables of the database as a read-only JSON API.
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"logger"
+	"metrics"
+	"net/http"
+	"status"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type (
+	// Resource is a table exposed by the API. Its columns are text, unless
+	// typed otherwise.
+	Resource struct {
+		Table   string
+		Columns []string
+		Types   map[string]Type
+		Embeds  map[string]Embed
+	}
+
+	// Type is the type of a column, by which the values of its filters are
+	// parsed.
+	Type int
+
+	// Embed is a relation of a resource which can be embedded in its
+	// representation.
+	Embed struct {
+		Resource string
+		Local    string
+		Foreign  string
+		Many     bool
+	}
+
+	// Page is the representation of a list of rows.
+	Page struct {
+		Data   []Row `json:"data"`
+		Total  int   `json:"total"`
+		Limit  int   `json:"limit"`
+		Offset int   `json:"offset"`
+	}
+
+	Row map[string]interface{}
+)
+
+const (
+	DefaultLimit = 50
+	MaxLimit     = 500
+)
+
+const (
+	Text Type = iota
+	Integer
+	Boolean
+)
+
+var Resources = map[string]Resource{
+	"teams": {
+		Table:   "team",
+		Columns: []string{"id", "name", "country", "country_code"},
+		Types:   map[string]Type{"id": Integer},
+		Embeds: map[string]Embed{
+			"players": {Resource: "players", Local: "id", Foreign: "team_id", Many: true},
+		},
+	},
+	"players": {
+		Table:   "player",
+		Columns: []string{"id", "name", "faction", "team_id", "identity_id"},
+		Types:   map[string]Type{"id": Integer, "team_id": Integer, "identity_id": Integer},
+		Embeds: map[string]Embed{
+			"team":  {Resource: "teams", Local: "team_id", Foreign: "id"},
+			"lists": {Resource: "lists", Local: "id", Foreign: "player_id", Many: true},
+		},
+	},
+	"lists": {
+		Table:   "list",
+		Columns: []string{"id", "caster", "faction", "player_id"},
+		Types:   map[string]Type{"id": Integer, "player_id": Integer},
+		Embeds: map[string]Embed{
+			"player":  {Resource: "players", Local: "player_id", Foreign: "id"},
+			"reports": {Resource: "reports", Local: "id", Foreign: "list_id", Many: true},
+		},
+	},
+	"matches": {
+		Table:   "match",
+		Columns: []string{"id", "round", "zone"},
+		Types:   map[string]Type{"id": Integer, "round": Integer, "zone": Integer},
+		Embeds: map[string]Embed{
+			"games": {Resource: "games", Local: "id", Foreign: "match_id", Many: true},
+		},
+	},
+	"games": {
+		Table:   "game",
+		Columns: []string{"id", "match_id"},
+		Types:   map[string]Type{"id": Integer, "match_id": Integer},
+		Embeds: map[string]Embed{
+			"match":   {Resource: "matches", Local: "match_id", Foreign: "id"},
+			"reports": {Resource: "reports", Local: "id", Foreign: "game_id", Many: true},
+		},
+	},
+	"reports": {
+		Table:   "report",
+		Columns: []string{"id", "game_id", "list_id", "won"},
+		Types:   map[string]Type{"id": Integer, "game_id": Integer, "list_id": Integer, "won": Boolean},
+		Embeds: map[string]Embed{
+			"game": {Resource: "games", Local: "game_id", Foreign: "id"},
+			"list": {Resource: "lists", Local: "list_id", Foreign: "id"},
+		},
+	},
+}
+
+// Server serves the resources of the database.
+type Server struct {
+	db              *sqlx.DB
+	log             *logger.Logger
+	run             *status.Run
+	requests        *metrics.Counter
+	requestDuration *metrics.Histogram
+}
+
+// New returns a server of the database, which logs its requests, reports
+// the errors of the database to the run and records its metrics in the
+// registry.
+func New(db *sqlx.DB, log *logger.Logger, run *status.Run, registry *metrics.Registry) *Server {
+	return &Server{
+		db:  db,
+		log: log,
+		run: run,
+		requests: registry.Counter(
+			"server_requests_total",
+			"Number of requests served, by response status.",
+			"status",
+		),
+		requestDuration: registry.Histogram(
+			"server_request_duration_seconds",
+			"Duration of the requests served.",
+			metrics.DefaultBuckets,
+		),
+	}
+}
+
+// ServeHTTP handles the /<resource> and /<resource>/<id> routes.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var start = time.Now()
+	var code, body = s.serve(r)
+
+	s.log.Info("serving request", logger.M{
+		"method": r.Method,
+		"url":    r.URL,
+		"status": code,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+
+	s.requests.Inc(strconv.Itoa(code))
+	s.requestDuration.Observe(time.Since(start).Seconds())
+}
+
+func (s *Server) serve(r *http.Request) (int, interface{}) {
+	if r.Method != http.MethodGet {
+		return http.StatusMethodNotAllowed, fail("method not allowed")
+	}
+
+	var parts = strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	resource, found := Resources[parts[0]]
+	if !found || len(parts) > 2 {
+		return http.StatusNotFound, fail("not found")
+	}
+
+	var query = r.URL.Query()
+	var embeds = split(query.Get("embed"))
+	for _, e := range embeds {
+		if _, found := resource.Embeds[e]; !found {
+			return http.StatusBadRequest, fail(fmt.Sprintf("unknown embed %q", e))
+		}
+	}
+
+	if len(parts) == 2 {
+		id, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return http.StatusNotFound, fail("not found")
+		}
+
+		rows, err := s.find(resource, map[string][]interface{}{"id": {id}}, 1, 0)
+		if err != nil {
+			return s.error(r, err)
+		}
+
+		if len(rows) == 0 {
+			return http.StatusNotFound, fail("not found")
+		}
+
+		err = s.embed(resource, rows, embeds)
+		if err != nil {
+			return s.error(r, err)
+		}
+
+		return http.StatusOK, rows[0]
+	}
+
+	var filters = make(map[string][]interface{})
+	for _, col := range resource.Columns {
+		for _, v := range query[col] {
+			value, err := resource.Types[col].parse(v)
+			if err != nil {
+				return http.StatusBadRequest, fail(fmt.Sprintf("invalid %s %q", col, v))
+			}
+			filters[col] = append(filters[col], value)
+		}
+	}
+
+	var limit, offset = DefaultLimit, 0
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > MaxLimit {
+			return http.StatusBadRequest, fail(fmt.Sprintf("limit must be between 1 and %d", MaxLimit))
+		}
+		limit = n
+	}
+	if v := query.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return http.StatusBadRequest, fail("offset must be a positive integer")
+		}
+		offset = n
+	}
+
+	total, err := s.count(resource, filters)
+	if err != nil {
+		return s.error(r, err)
+	}
+
+	rows, err := s.find(resource, filters, limit, offset)
+	if err != nil {
+		return s.error(r, err)
+	}
+
+	err = s.embed(resource, rows, embeds)
+	if err != nil {
+		return s.error(r, err)
+	}
+
+	return http.StatusOK, Page{
+		Data:   rows,
+		Total:  total,
+		Limit:  limit,
+		Offset: offset,
+	}
+}
+
+func (s *Server) error(r *http.Request, err error) (int, interface{}) {
+	s.run.Error("querying database", logger.M{
+		"url": r.URL,
+		"err": err,
+	})
+	return http.StatusInternalServerError, fail("internal error")
+}
+
+// where builds the condition matching the filters, each filter matching any
+// of its values.
+func where(filters map[string][]interface{}) (string, []interface{}, error) {
+	if len(filters) == 0 {
+		return "", nil, nil
+	}
+
+	var conds []string
+	var args []interface{}
+	for col, values := range filters {
+		cond, a, err := sqlx.In(col+" in (?)", values)
+		if err != nil {
+			return "", nil, err
+		}
+		conds = append(conds, cond)
+		args = append(args, a...)
+	}
+
+	return " where " + strings.Join(conds, " and "), args, nil
+}
+
+func (s *Server) count(resource Resource, filters map[string][]interface{}) (int, error) {
+	cond, args, err := where(filters)
+	if err != nil {
+		return 0, err
+	}
+
+	var total int
+	err = s.db.Get(&total, "select count(*) from "+resource.Table+cond, args...)
+	return total, err
+}
+
+func (s *Server) find(resource Resource, filters map[string][]interface{}, limit, offset int) ([]Row, error) {
+	cond, args, err := where(filters)
+	if err != nil {
+		return nil, err
+	}
+
+	var query = "select " + strings.Join(resource.Columns, ", ") + " from " + resource.Table + cond + " order by id"
+	if limit > 0 {
+		query += fmt.Sprintf(" limit %d offset %d", limit, offset)
+	}
+
+	res, err := s.db.Queryx(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+
+	var rows = []Row{}
+	for res.Next() {
+		var row = make(map[string]interface{})
+		err = res.MapScan(row)
+		if err != nil {
+			return nil, err
+		}
+
+		for k, v := range row {
+			if b, ok := v.([]byte); ok {
+				row[k] = string(b)
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, res.Err()
+}
+
+// embed adds the related rows to each row, in one query per relation.
+func (s *Server) embed(resource Resource, rows []Row, embeds []string) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	for _, name := range embeds {
+		var e = resource.Embeds[name]
+
+		var keys []interface{}
+		for _, row := range rows {
+			keys = append(keys, row[e.Local])
+		}
+
+		related, err := s.find(Resources[e.Resource], map[string][]interface{}{e.Foreign: keys}, 0, 0)
+		if err != nil {
+			return err
+		}
+
+		var index = make(map[string][]Row)
+		for _, r := range related {
+			var key = fmt.Sprint(r[e.Foreign])
+			index[key] = append(index[key], r)
+		}
+
+		for _, row := range rows {
+			var matches = index[fmt.Sprint(row[e.Local])]
+			if e.Many {
+				if matches == nil {
+					matches = []Row{}
+				}
+				row[name] = matches
+			} else if len(matches) > 0 {
+				row[name] = matches[0]
+			} else {
+				row[name] = nil
+			}
+		}
+	}
+
+	return nil
+}
+
+// parse returns the value of a filter on a column of the type.
+func (t Type) parse(v string) (interface{}, error) {
+	switch t {
+	case Integer:
+		return strconv.ParseInt(v, 10, 64)
+	case Boolean:
+		return strconv.ParseBool(v)
+	}
+	return v, nil
+}
+
+func split(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+func fail(msg string) interface{} {
+	return map[string]string{
+		"error": msg,
+	}
+}
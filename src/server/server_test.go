@@ -0,0 +1,113 @@
+package server
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"logger"
+	"metrics"
+	"status"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestFilters(t *testing.T) {
+	dir, err := ioutil.TempDir("", "server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := sqlx.Connect("sqlite3", filepath.Join(dir, "test.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for _, stmt := range []string{
+		"create table match ( id integer primary key, round integer, zone integer )",
+		"create table game ( id integer primary key, match_id integer )",
+		"create table report ( id integer primary key, game_id integer, list_id integer, won boolean )",
+		"insert into match (round, zone) values (1, 12), (2, 12)",
+		"insert into game (match_id) values (1), (2)",
+	} {
+		db.MustExec(stmt)
+	}
+	for _, won := range []bool{true, false, false, true} {
+		db.MustExec("insert into report (game_id, list_id, won) values (1, 1, ?)", won)
+	}
+
+	var log = logger.New(ioutil.Discard)
+	var server = httptest.NewServer(New(db, log, status.New(log), metrics.NewRegistry()))
+	defer server.Close()
+
+	for _, c := range []struct {
+		url   string
+		code  int
+		total int
+	}{
+		{"/reports?won=true", http.StatusOK, 2},
+		{"/reports?won=false", http.StatusOK, 2},
+		{"/reports?won=1&won=0", http.StatusOK, 4},
+		{"/reports?won=maybe", http.StatusBadRequest, 0},
+		{"/matches?round=2", http.StatusOK, 1},
+		{"/matches?round=two", http.StatusBadRequest, 0},
+		{"/games?match_id=1&embed=match", http.StatusOK, 1},
+	} {
+		res, err := http.Get(server.URL + c.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var page Page
+		_ = json.NewDecoder(res.Body).Decode(&page)
+		res.Body.Close()
+
+		if res.StatusCode != c.code {
+			t.Errorf("%s: expected status %d, got %d", c.url, c.code, res.StatusCode)
+		}
+		if page.Total != c.total {
+			t.Errorf("%s: expected %d rows, got %d", c.url, c.total, page.Total)
+		}
+	}
+
+	// The keys of the embedded rows are bound as they were read.
+	res, err := http.Get(server.URL + "/games/2?embed=match")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var game struct {
+		Match struct {
+			Round int `json:"round"`
+		} `json:"match"`
+	}
+	_ = json.NewDecoder(res.Body).Decode(&game)
+	res.Body.Close()
+	if game.Match.Round != 2 {
+		t.Errorf("expected the match of round 2 to be embedded, got %+v", game)
+	}
+
+	for _, c := range []struct {
+		url  string
+		code int
+	}{
+		{"/matches/2", http.StatusOK},
+		{"/matches/3", http.StatusNotFound},
+		{"/matches/two", http.StatusNotFound},
+	} {
+		res, err := http.Get(server.URL + c.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+
+		if res.StatusCode != c.code {
+			t.Errorf("%s: expected status %d, got %d", c.url, c.code, res.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,416 @@
+// Package site builds a static website of the results from the database.
+package site
+
+import (
+	"fmt"
+	"html/template"
+	"logger"
+	"os"
+	"path/filepath"
+	"sort"
+	"status"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type (
+	Site struct {
+		Teams    []*Team
+		Players  []*Player
+		Casters  []*Caster
+		Factions []*Faction
+		Rounds   []*Round
+	}
+
+	Record struct {
+		Wins   int
+		Losses int
+	}
+
+	Team struct {
+		ID      int    `db:"id"`
+		Name    string `db:"name"`
+		Country string `db:"country"`
+		Players []*Player
+		Matches []*Match
+		Record
+	}
+
+	Player struct {
+		ID      int    `db:"id"`
+		Name    string `db:"name"`
+		Faction string `db:"faction"`
+		TeamID  int    `db:"team_id"`
+		Team    *Team
+		Lists   []*List
+		Games   []*Game
+		Record
+	}
+
+	List struct {
+		ID       int    `db:"id"`
+		Caster   string `db:"caster"`
+		Faction  string `db:"faction"`
+		PlayerID int    `db:"player_id"`
+		Player   *Player
+		Record
+	}
+
+	Caster struct {
+		Name    string
+		Faction string
+		Lists   []*List
+		Record
+	}
+
+	Faction struct {
+		Name    string
+		Casters []*Caster
+		Players []*Player
+		Record
+	}
+
+	Round struct {
+		Number  int
+		Matches []*Match
+	}
+
+	Match struct {
+		ID    int    `db:"id"`
+		Round int    `db:"round"`
+		Zone  string `db:"zone"`
+		Teams [2]*Team
+		Score [2]int
+		Games []*Game
+	}
+
+	Game struct {
+		ID      int `db:"id"`
+		MatchID int `db:"match_id"`
+		Match   *Match
+		Reports []*Report
+	}
+
+	Report struct {
+		ID     int  `db:"id"`
+		GameID int  `db:"game_id"`
+		ListID int  `db:"list_id"`
+		Won    bool `db:"won"`
+		List   *List
+	}
+
+	// Output is a page of the site to render.
+	Output struct {
+		Path     string
+		Template string
+		Data     interface{}
+	}
+
+	// Page is the data given to the templates.
+	Page struct {
+		Root string
+		Site *Site
+		Data interface{}
+	}
+)
+
+// ParseTemplates returns the default templates, along with the templates of
+// the given directory, if any, which override the ones of the same name.
+func ParseTemplates(dir string) (*template.Template, error) {
+	var tpl = template.New("").Funcs(template.FuncMap{
+		"slug":    slug,
+		"percent": percent,
+	})
+	for name, text := range Templates {
+		template.Must(tpl.New(name).Parse(text))
+	}
+	if dir == "" {
+		return tpl, nil
+	}
+	return tpl.ParseGlob(filepath.Join(dir, "*"))
+}
+
+// Pages returns the pages of the site.
+func (s *Site) Pages() []Output {
+	var pages = []Output{
+		{"index.html", "index.html", nil},
+	}
+	for _, t := range s.Teams {
+		pages = append(pages, Output{fmt.Sprintf("teams/%d.html", t.ID), "team.html", t})
+	}
+	for _, p := range s.Players {
+		pages = append(pages, Output{fmt.Sprintf("players/%d.html", p.ID), "player.html", p})
+	}
+	for _, c := range s.Casters {
+		pages = append(pages, Output{fmt.Sprintf("casters/%s.html", slug(c.Name)), "caster.html", c})
+	}
+	for _, f := range s.Factions {
+		pages = append(pages, Output{fmt.Sprintf("factions/%s.html", slug(f.Name)), "faction.html", f})
+	}
+	for _, r := range s.Rounds {
+		pages = append(pages, Output{fmt.Sprintf("rounds/%d.html", r.Number), "round.html", r})
+	}
+	return pages
+}
+
+// Render executes the template of a page of the site into its file of the
+// given directory.
+func (s *Site) Render(tpl *template.Template, dir string, page Output) error {
+	var path = filepath.Join(dir, page.Path)
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	err = tpl.ExecuteTemplate(file, page.Template, Page{
+		Root: strings.Repeat("../", strings.Count(page.Path, "/")),
+		Site: s,
+		Data: page.Data,
+	})
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// Load reads the whole database and links the entities together. The reports
+// which can't be linked to a side of their match are reported to the run and
+// skipped.
+func Load(db *sqlx.DB, run *status.Run) (*Site, error) {
+	var site = &Site{}
+	var matches []*Match
+	var games []*Game
+	var lists []*List
+	var reports []*Report
+
+	for _, q := range []struct {
+		dest  interface{}
+		query string
+	}{
+		{&site.Teams, "select id, name, country from team order by name"},
+		{&site.Players, "select id, name, faction, team_id from player order by name"},
+		{&lists, "select id, caster, faction, player_id from list order by id"},
+		{&matches, "select id, round, zone from match order by round, zone"},
+		{&games, "select id, match_id from game order by id"},
+		{&reports, "select id, game_id, list_id, won from report order by id"},
+	} {
+		err := db.Select(q.dest, q.query)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var teams = make(map[int]*Team)
+	for _, t := range site.Teams {
+		teams[t.ID] = t
+	}
+
+	var factions = make(map[string]*Faction)
+	var faction = func(name string) *Faction {
+		var f = factions[name]
+		if f == nil {
+			f = &Faction{Name: name}
+			factions[name] = f
+			site.Factions = append(site.Factions, f)
+		}
+		return f
+	}
+
+	var players = make(map[int]*Player)
+	for _, p := range site.Players {
+		players[p.ID] = p
+		p.Team = teams[p.TeamID]
+		if p.Team != nil {
+			p.Team.Players = append(p.Team.Players, p)
+		}
+
+		var f = faction(p.Faction)
+		f.Players = append(f.Players, p)
+	}
+
+	var byID = make(map[int]*List)
+	var casters = make(map[string]*Caster)
+	for _, l := range lists {
+		byID[l.ID] = l
+		l.Player = players[l.PlayerID]
+		if l.Player != nil {
+			l.Player.Lists = append(l.Player.Lists, l)
+		}
+
+		var c = casters[l.Caster]
+		if c == nil {
+			c = &Caster{Name: l.Caster, Faction: l.Faction}
+			casters[l.Caster] = c
+			site.Casters = append(site.Casters, c)
+		}
+		c.Lists = append(c.Lists, l)
+	}
+
+	var rounds = make(map[int]*Round)
+	var byMatch = make(map[int]*Match)
+	for _, m := range matches {
+		byMatch[m.ID] = m
+
+		var r = rounds[m.Round]
+		if r == nil {
+			r = &Round{Number: m.Round}
+			rounds[m.Round] = r
+			site.Rounds = append(site.Rounds, r)
+		}
+		r.Matches = append(r.Matches, m)
+	}
+
+	var byGame = make(map[int]*Game)
+	for _, g := range games {
+		byGame[g.ID] = g
+		g.Match = byMatch[g.MatchID]
+		if g.Match != nil {
+			g.Match.Games = append(g.Match.Games, g)
+		}
+	}
+
+	for _, r := range reports {
+		r.List = byID[r.ListID]
+		var g = byGame[r.GameID]
+		if g == nil || r.List == nil || r.List.Player == nil {
+			continue
+		}
+		if len(g.Reports) == 2 {
+			run.Error("extra report", logger.M{
+				"report_id": r.ID,
+				"game_id":   g.ID,
+			})
+			continue
+		}
+
+		var p = r.List.Player
+		if g.Match != nil {
+			side, ok := g.Match.side(p.Team)
+			if !ok {
+				run.Error("report of neither team", logger.M{
+					"report_id": r.ID,
+					"match_id":  g.Match.ID,
+					"player":    p.Name,
+				})
+				continue
+			}
+			if r.Won {
+				g.Match.Score[side]++
+			}
+		}
+
+		g.Reports = append(g.Reports, r)
+		p.Games = append(p.Games, g)
+		for _, record := range []*Record{&r.List.Record, &p.Record, &casters[r.List.Caster].Record, &faction(r.List.Faction).Record} {
+			record.add(r.Won)
+		}
+	}
+
+	for _, m := range matches {
+		for i, t := range m.Teams {
+			if t != nil {
+				t.add(m.Score[i] > m.Score[1-i])
+			}
+		}
+	}
+
+	for _, c := range site.Casters {
+		var f = faction(c.Faction)
+		f.Casters = append(f.Casters, c)
+	}
+
+	sort.Slice(site.Casters, func(i, j int) bool { return site.Casters[i].Name < site.Casters[j].Name })
+	sort.Slice(site.Factions, func(i, j int) bool { return site.Factions[i].Name < site.Factions[j].Name })
+	sort.Slice(site.Rounds, func(i, j int) bool { return site.Rounds[i].Number < site.Rounds[j].Number })
+
+	return site, nil
+}
+
+func (r *Record) add(won bool) {
+	if won {
+		r.Wins++
+	} else {
+		r.Losses++
+	}
+}
+
+// Games returns the number of games of the record.
+func (r Record) Games() int {
+	return r.Wins + r.Losses
+}
+
+// WinRate returns the ratio of won games of the record.
+func (r Record) WinRate() float64 {
+	if r.Games() == 0 {
+		return 0
+	}
+	return float64(r.Wins) / float64(r.Games())
+}
+
+// side returns the side of the given team in the match, the teams of a match
+// being the first two teams of its reports. It fails if the team is unknown
+// or the match already has two other teams.
+func (m *Match) side(t *Team) (int, bool) {
+	if t == nil {
+		return 0, false
+	}
+	for i := range m.Teams {
+		if m.Teams[i] == t {
+			return i, true
+		}
+		if m.Teams[i] == nil {
+			m.Teams[i] = t
+			t.Matches = append(t.Matches, m)
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// Opponent returns the report of the other side of the game.
+func (g *Game) Opponent(p *Player) *Report {
+	for _, r := range g.Reports {
+		if r.List != nil && r.List.Player != p {
+			return r
+		}
+	}
+	return nil
+}
+
+// Own returns the report of the given player in the game.
+func (g *Game) Own(p *Player) *Report {
+	for _, r := range g.Reports {
+		if r.List != nil && r.List.Player == p {
+			return r
+		}
+	}
+	return nil
+}
+
+func slug(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return strings.Trim(strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		}
+		return '-'
+	}, s), "-")
+}
+
+func percent(f float64) string {
+	return fmt.Sprintf("%.1f%%", f*100)
+}
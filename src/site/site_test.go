@@ -0,0 +1,74 @@
+package site
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"logger"
+	"status"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestLoadSides(t *testing.T) {
+	var run = status.New(logger.New(ioutil.Discard))
+
+	dir, err := ioutil.TempDir("", "site")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := sqlx.Connect("sqlite3", filepath.Join(dir, "test.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// The first game misses the report of team A, the second has a report
+	// too many, and the third a player of a third team.
+	for _, stmt := range []string{
+		"create table team ( id integer primary key, name varchar(50), country varchar(50), country_code varchar(6) )",
+		"create table player ( id integer primary key, name varchar(50), faction varchar(50), team_id integer, identity_id integer )",
+		"create table list ( id integer primary key, caster varchar(50), faction varchar(50), player_id integer )",
+		"create table match ( id integer primary key, round integer, zone integer )",
+		"create table game ( id integer primary key, match_id integer )",
+		"create table report ( id integer primary key, game_id integer, list_id integer, won boolean )",
+		"insert into team (name, country) values ('A', 'France'), ('B', 'Germany'), ('C', 'Italy')",
+		"insert into player (name, faction, team_id) values ('a1', 'Cygnar', 1), ('b1', 'Cryx', 2), ('a2', 'Cygnar', 1), ('b2', 'Cryx', 2), ('c1', 'Cryx', 3)",
+		"insert into list (caster, faction, player_id) values ('Haley 1', 'Cygnar', 1), ('Deneghra 1', 'Cryx', 2), ('Haley 1', 'Cygnar', 3), ('Deneghra 1', 'Cryx', 4), ('Deneghra 1', 'Cryx', 5)",
+		"insert into match (round, zone) values (1, 1)",
+		"insert into game (match_id) values (1), (1), (1)",
+		"insert into report (game_id, list_id, won) values (1, 2, 1)",
+		"insert into report (game_id, list_id, won) values (2, 3, 1), (2, 4, 0), (2, 2, 1)",
+		"insert into report (game_id, list_id, won) values (3, 1, 1), (3, 5, 0)",
+	} {
+		db.MustExec(stmt)
+	}
+
+	site, err := Load(db, run)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var m = site.Rounds[0].Matches[0]
+	if m.Teams[0] == nil || m.Teams[0].Name != "B" || m.Teams[1] == nil || m.Teams[1].Name != "A" {
+		t.Fatalf("expected the teams B and A, got %v and %v", m.Teams[0], m.Teams[1])
+	}
+	if m.Score != [2]int{1, 2} {
+		t.Errorf("expected the score 1 - 2, got %v", m.Score)
+	}
+	if n := run.Errors(); n != 2 {
+		t.Errorf("expected 2 errors, got %d", n)
+	}
+
+	for _, team := range site.Teams {
+		var expected = map[string]int{"A": 1, "B": 1, "C": 0}[team.Name]
+		if len(team.Matches) != expected {
+			t.Errorf("expected %d matches for team %s, got %d", expected, team.Name, len(team.Matches))
+		}
+	}
+}
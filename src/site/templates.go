@@ -0,0 +1,126 @@
+package site
+
+// Templates are the default templates of the site, by name. Each page
+// template is executed with a Page, and the "header" and "footer" templates
+// are shared by every page.
+var Templates = map[string]string{
+	"header": `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.}} - WTC</title>
+<style>
+body { font-family: sans-serif; margin: 2em auto; max-width: 60em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border-bottom: 1px solid #ddd; padding: 0.3em 0.8em; text-align: left; }
+.won { color: #070; }
+.lost { color: #a00; }
+</style>
+</head>
+<body>`,
+
+	"nav": `<nav><a href="{{.Root}}index.html">Home</a></nav>`,
+
+	"footer": `</body>
+</html>
+`,
+
+	"record": `<td>{{.Games}}</td><td>{{.Wins}}</td><td>{{.Losses}}</td><td>{{percent .WinRate}}</td>`,
+
+	"index.html": `{{template "header" "Results"}}
+<h1>WTC results</h1>
+<h2>Rounds</h2>
+<ul>
+{{range .Site.Rounds}}<li><a href="rounds/{{.Number}}.html">Round {{.Number}}</a></li>
+{{end}}</ul>
+<h2>Teams</h2>
+<table>
+<tr><th>Team</th><th>Country</th><th>Matches</th><th>Wins</th><th>Losses</th><th>Win rate</th></tr>
+{{range .Site.Teams}}<tr><td><a href="teams/{{.ID}}.html">{{.Name}}</a></td><td>{{.Country}}</td>{{template "record" .Record}}</tr>
+{{end}}</table>
+<h2>Factions</h2>
+<table>
+<tr><th>Faction</th><th>Games</th><th>Wins</th><th>Losses</th><th>Win rate</th></tr>
+{{range .Site.Factions}}<tr><td><a href="factions/{{slug .Name}}.html">{{.Name}}</a></td>{{template "record" .Record}}</tr>
+{{end}}</table>
+<h2>Casters</h2>
+<table>
+<tr><th>Caster</th><th>Faction</th><th>Games</th><th>Wins</th><th>Losses</th><th>Win rate</th></tr>
+{{range .Site.Casters}}<tr><td><a href="casters/{{slug .Name}}.html">{{.Name}}</a></td><td>{{.Faction}}</td>{{template "record" .Record}}</tr>
+{{end}}</table>
+{{template "footer"}}`,
+
+	"team.html": `{{template "header" .Data.Name}}
+{{template "nav" .}}
+{{$root := .Root}}{{$team := .Data}}
+<h1>{{.Data.Name}} ({{.Data.Country}})</h1>
+<p>{{.Data.Wins}} matches won, {{.Data.Losses}} lost.</p>
+<h2>Roster</h2>
+<table>
+<tr><th>Player</th><th>Faction</th><th>Games</th><th>Wins</th><th>Losses</th><th>Win rate</th></tr>
+{{range .Data.Players}}<tr><td><a href="{{$root}}players/{{.ID}}.html">{{.Name}}</a></td><td>{{.Faction}}</td>{{template "record" .Record}}</tr>
+{{end}}</table>
+<h2>Matches</h2>
+<table>
+<tr><th>Round</th><th>Zone</th><th>Opponent</th><th>Score</th><th>Result</th></tr>
+{{range .Data.Matches}}{{$us := index .Teams 0}}{{$usScore := index .Score 0}}{{$them := index .Teams 1}}{{$themScore := index .Score 1}}{{if ne $us $team}}{{$us = index .Teams 1}}{{$usScore = index .Score 1}}{{$them = index .Teams 0}}{{$themScore = index .Score 0}}{{end}}<tr><td><a href="{{$root}}rounds/{{.Round}}.html">{{.Round}}</a></td><td>{{.Zone}}</td><td>{{with $them}}<a href="{{$root}}teams/{{.ID}}.html">{{.Name}}</a>{{end}}</td><td>{{$usScore}} - {{$themScore}}</td><td>{{if gt $usScore $themScore}}<span class="won">won</span>{{else}}<span class="lost">lost</span>{{end}}</td></tr>
+{{end}}</table>
+{{template "footer"}}`,
+
+	"player.html": `{{template "header" .Data.Name}}
+{{template "nav" .}}
+{{$root := .Root}}{{$player := .Data}}
+<h1>{{.Data.Name}}</h1>
+<p>{{.Data.Faction}}{{with .Data.Team}}, <a href="{{$root}}teams/{{.ID}}.html">{{.Name}}</a>{{end}}. {{.Data.Wins}} games won, {{.Data.Losses}} lost ({{percent .Data.WinRate}}).</p>
+<h2>Lists</h2>
+<table>
+<tr><th>Caster</th><th>Games</th><th>Wins</th><th>Losses</th><th>Win rate</th></tr>
+{{range .Data.Lists}}<tr><td><a href="{{$root}}casters/{{slug .Caster}}.html">{{.Caster}}</a></td>{{template "record" .Record}}</tr>
+{{end}}</table>
+<h2>Games</h2>
+<table>
+<tr><th>Round</th><th>Caster</th><th>Opponent</th><th>Opponent caster</th><th>Result</th></tr>
+{{range .Data.Games}}{{$own := .Own $player}}{{$opponent := .Opponent $player}}<tr><td>{{with .Match}}<a href="{{$root}}rounds/{{.Round}}.html">{{.Round}}</a>{{end}}</td><td>{{$own.List.Caster}}</td><td>{{with $opponent}}<a href="{{$root}}players/{{.List.Player.ID}}.html">{{.List.Player.Name}}</a>{{end}}</td><td>{{with $opponent}}{{.List.Caster}}{{end}}</td><td>{{if $own.Won}}<span class="won">won</span>{{else}}<span class="lost">lost</span>{{end}}</td></tr>
+{{end}}</table>
+{{template "footer"}}`,
+
+	"caster.html": `{{template "header" .Data.Name}}
+{{template "nav" .}}
+{{$root := .Root}}
+<h1>{{.Data.Name}}</h1>
+<p><a href="{{$root}}factions/{{slug .Data.Faction}}.html">{{.Data.Faction}}</a>. {{.Data.Games}} games, {{.Data.Wins}} won ({{percent .Data.WinRate}}).</p>
+<table>
+<tr><th>Player</th><th>Games</th><th>Wins</th><th>Losses</th><th>Win rate</th></tr>
+{{range .Data.Lists}}<tr><td>{{with .Player}}<a href="{{$root}}players/{{.ID}}.html">{{.Name}}</a>{{end}}</td>{{template "record" .Record}}</tr>
+{{end}}</table>
+{{template "footer"}}`,
+
+	"faction.html": `{{template "header" .Data.Name}}
+{{template "nav" .}}
+{{$root := .Root}}
+<h1>{{.Data.Name}}</h1>
+<p>{{.Data.Games}} games, {{.Data.Wins}} won ({{percent .Data.WinRate}}).</p>
+<h2>Casters</h2>
+<table>
+<tr><th>Caster</th><th>Games</th><th>Wins</th><th>Losses</th><th>Win rate</th></tr>
+{{range .Data.Casters}}<tr><td><a href="{{$root}}casters/{{slug .Name}}.html">{{.Name}}</a></td>{{template "record" .Record}}</tr>
+{{end}}</table>
+<h2>Players</h2>
+<table>
+<tr><th>Player</th><th>Games</th><th>Wins</th><th>Losses</th><th>Win rate</th></tr>
+{{range .Data.Players}}<tr><td><a href="{{$root}}players/{{.ID}}.html">{{.Name}}</a></td>{{template "record" .Record}}</tr>
+{{end}}</table>
+{{template "footer"}}`,
+
+	"round.html": `{{template "header" (printf "Round %d" .Data.Number)}}
+{{template "nav" .}}
+{{$root := .Root}}
+<h1>Round {{.Data.Number}}</h1>
+{{range .Data.Matches}}
+<h2>Zone {{.Zone}}: {{with index .Teams 0}}<a href="{{$root}}teams/{{.ID}}.html">{{.Name}}</a>{{end}} {{index .Score 0}} - {{index .Score 1}} {{with index .Teams 1}}<a href="{{$root}}teams/{{.ID}}.html">{{.Name}}</a>{{end}}</h2>
+<table>
+{{range .Games}}<tr>{{range .Reports}}<td class="{{if .Won}}won{{else}}lost{{end}}"><a href="{{$root}}players/{{.List.Player.ID}}.html">{{.List.Player.Name}}</a> ({{.List.Caster}})</td>{{end}}</tr>
+{{end}}</table>
+{{end}}
+{{template "footer"}}`,
+}
@@ -0,0 +1,47 @@
+package stats
+
+import (
+	"math"
+)
+
+// DefaultRating is the rating of the players before their first game.
+const DefaultRating = 1500
+
+// Elo rates players from the results of their games: after each game, the
+// winner takes from the loser a number of points growing with how unexpected
+// the result was.
+type Elo struct {
+	// K is the maximum number of points exchanged in a game.
+	K float64
+
+	ratings map[int]float64
+}
+
+// NewElo returns a rating of players exchanging at most k points per game.
+func NewElo(k float64) *Elo {
+	return &Elo{
+		K:       k,
+		ratings: make(map[int]float64),
+	}
+}
+
+// Rating returns the rating of the player.
+func (e *Elo) Rating(player int) float64 {
+	if r, found := e.ratings[player]; found {
+		return r
+	}
+	return DefaultRating
+}
+
+// Expected returns the probability that the player beats the opponent given
+// their ratings.
+func (e *Elo) Expected(player, opponent int) float64 {
+	return 1 / (1 + math.Pow(10, (e.Rating(opponent)-e.Rating(player))/400))
+}
+
+// Update rates the players of a game from its result.
+func (e *Elo) Update(winner, loser int) {
+	var delta = e.K * (1 - e.Expected(winner, loser))
+	e.ratings[winner] = e.Rating(winner) + delta
+	e.ratings[loser] = e.Rating(loser) - delta
+}
@@ -0,0 +1,170 @@
+package stats
+
+import (
+	"math"
+)
+
+// An Interval is a range of plausible values of a proportion.
+type Interval struct {
+	Low  float64 `json:"low"`
+	High float64 `json:"high"`
+}
+
+// Z returns the quantile of the standard normal distribution giving a
+// two-sided interval of the given level, 1.96 for 0.95 for example.
+func Z(level float64) float64 {
+	return math.Sqrt2 * math.Erfinv(level)
+}
+
+// Wilson returns the Wilson score interval of the proportion of wins out of n
+// games, at the given level. Unlike the normal approximation, it stays within
+// [0, 1] and behaves on small samples and extreme proportions.
+func Wilson(wins, n int, level float64) Interval {
+	if n == 0 {
+		return Interval{0, 1}
+	}
+
+	var z = Z(level)
+	var p = float64(wins) / float64(n)
+	var nf = float64(n)
+	var denominator = 1 + z*z/nf
+	var center = (p + z*z/(2*nf)) / denominator
+	var margin = z / denominator * math.Sqrt(p*(1-p)/nf+z*z/(4*nf*nf))
+	return Interval{
+		Low:  math.Max(0, center-margin),
+		High: math.Min(1, center+margin),
+	}
+}
+
+// Credible returns the equal-tailed credible interval of the proportion of
+// wins out of n games at the given level, for a Beta(a, b) prior: a = b = 1
+// is the uniform prior, a = b = 0.5 the Jeffreys prior.
+func Credible(wins, n int, a, b, level float64) Interval {
+	var alpha = a + float64(wins)
+	var beta = b + float64(n-wins)
+	return Interval{
+		Low:  BetaQuantile((1-level)/2, alpha, beta),
+		High: BetaQuantile((1+level)/2, alpha, beta),
+	}
+}
+
+// BinomialTest returns the two-sided p-value of the exact binomial test of
+// wins out of n games against the proportion p: the probability, were p the
+// true proportion, of an outcome at most as likely as the observed one.
+func BinomialTest(wins, n int, p float64) float64 {
+	if n == 0 {
+		return 1
+	}
+
+	// The outcomes are compared with a relative tolerance, so the outcomes
+	// as likely as the observed one aren't missed to rounding errors.
+	var observed = binomialLogPMF(wins, n, p)
+	var total float64
+	for k := 0; k <= n; k++ {
+		if lp := binomialLogPMF(k, n, p); lp <= observed+1e-7 {
+			total += math.Exp(lp)
+		}
+	}
+	return math.Min(1, total)
+}
+
+func binomialLogPMF(k, n int, p float64) float64 {
+	switch {
+	case p == 0 && k == 0, p == 1 && k == n:
+		return 0
+	case p == 0, p == 1:
+		return math.Inf(-1)
+	}
+
+	var coef = lgamma(float64(n+1)) - lgamma(float64(k+1)) - lgamma(float64(n-k+1))
+	return coef + float64(k)*math.Log(p) + float64(n-k)*math.Log(1-p)
+}
+
+// BetaCDF returns the cumulative distribution function of the Beta(a, b)
+// distribution at x, the regularized incomplete beta function.
+func BetaCDF(x, a, b float64) float64 {
+	switch {
+	case x <= 0:
+		return 0
+	case x >= 1:
+		return 1
+	}
+
+	var lbeta = lgamma(a+b) - lgamma(a) - lgamma(b)
+	var front = math.Exp(lbeta + a*math.Log(x) + b*math.Log(1-x))
+
+	// The continued fraction converges quickly for x below the mean, the
+	// symmetry of the function is used above it.
+	if x < (a+1)/(a+b+2) {
+		return front * betaFraction(x, a, b) / a
+	}
+	return 1 - front*betaFraction(1-x, b, a)/b
+}
+
+// BetaQuantile returns the quantile of the Beta(a, b) distribution at p, the
+// inverse of BetaCDF.
+func BetaQuantile(p, a, b float64) float64 {
+	switch {
+	case p <= 0:
+		return 0
+	case p >= 1:
+		return 1
+	}
+
+	// The CDF is increasing, a bisection always converges.
+	var low, high = 0.0, 1.0
+	for i := 0; i < 100; i++ {
+		var mid = (low + high) / 2
+		if BetaCDF(mid, a, b) < p {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	return (low + high) / 2
+}
+
+// betaFraction evaluates the continued fraction of the incomplete beta
+// function with the modified Lentz's method.
+func betaFraction(x, a, b float64) float64 {
+	const (
+		epsilon = 1e-15
+		tiny    = 1e-300
+	)
+
+	var c = 1.0
+	var d = 1 - (a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	var h = d
+
+	for m := 1; m <= 300; m++ {
+		var mf = float64(m)
+		for _, coef := range []float64{
+			mf * (b - mf) * x / ((a + 2*mf - 1) * (a + 2*mf)),
+			-(a + mf) * (a + b + mf) * x / ((a + 2*mf) * (a + 2*mf + 1)),
+		} {
+			d = 1 + coef*d
+			if math.Abs(d) < tiny {
+				d = tiny
+			}
+			c = 1 + coef/c
+			if math.Abs(c) < tiny {
+				c = tiny
+			}
+			d = 1 / d
+			h *= d * c
+		}
+		if math.Abs(d*c-1) < epsilon {
+			break
+		}
+	}
+	return h
+}
+
+func lgamma(x float64) float64 {
+	var v, _ = math.Lgamma(x)
+	return v
+}
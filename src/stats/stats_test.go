@@ -0,0 +1,116 @@
+package stats
+
+import (
+	"math"
+	"testing"
+)
+
+// near tells whether the value is the expected one to the given tolerance.
+func near(v, expected, tolerance float64) bool {
+	return math.Abs(v-expected) <= tolerance
+}
+
+func TestZ(t *testing.T) {
+	for _, c := range []struct {
+		level, z float64
+	}{
+		{0.90, 1.644854},
+		{0.95, 1.959964},
+		{0.99, 2.575829},
+	} {
+		if z := Z(c.level); !near(z, c.z, 1e-6) {
+			t.Errorf("Z(%g): expected %g, got %g", c.level, c.z, z)
+		}
+	}
+}
+
+// The intervals are those of Newcombe, "Two-sided confidence intervals for
+// the single proportion" (1998), table I, method 3, and of R's prop.test
+// without continuity correction.
+func TestWilson(t *testing.T) {
+	for _, c := range []struct {
+		wins, n   int
+		low, high float64
+	}{
+		{81, 263, 0.2553, 0.3662},
+		{15, 148, 0.0624, 0.1605},
+		{0, 20, 0, 0.1611},
+		{1, 29, 0.0061, 0.1718},
+		{8, 10, 0.4902, 0.9433},
+		{50, 100, 0.4038, 0.5962},
+		{0, 0, 0, 1},
+	} {
+		var i = Wilson(c.wins, c.n, 0.95)
+		if !near(i.Low, c.low, 5e-5) || !near(i.High, c.high, 5e-5) {
+			t.Errorf("Wilson(%d, %d): expected [%g, %g], got [%g, %g]", c.wins, c.n, c.low, c.high, i.Low, i.High)
+		}
+	}
+}
+
+// The reference values are exact for integer parameters, and those of R's
+// pbeta and qbeta otherwise.
+func TestBeta(t *testing.T) {
+	for _, c := range []struct {
+		x, a, b, p float64
+	}{
+		{0.5, 2, 3, 0.6875},
+		{0.5, 1, 1, 0.5},
+		{0.3, 1, 2, 0.51},
+		{0.2, 0.5, 0.5, 0.2951672},
+		{0.9, 10, 2, 0.6973569},
+	} {
+		if p := BetaCDF(c.x, c.a, c.b); !near(p, c.p, 1e-6) {
+			t.Errorf("BetaCDF(%g, %g, %g): expected %g, got %g", c.x, c.a, c.b, c.p, p)
+		}
+		if x := BetaQuantile(c.p, c.a, c.b); !near(x, c.x, 1e-6) {
+			t.Errorf("BetaQuantile(%g, %g, %g): expected %g, got %g", c.p, c.a, c.b, c.x, x)
+		}
+	}
+
+	// qbeta(c(0.025, 0.975), 9, 3)
+	var i = Credible(8, 10, 1, 1, 0.95)
+	if !near(i.Low, 0.4822, 5e-5) || !near(i.High, 0.9398, 5e-5) {
+		t.Errorf("Credible(8, 10): expected [0.4822, 0.9398], got [%g, %g]", i.Low, i.High)
+	}
+}
+
+// The p-values are exact for p = 0.5, and those of R's binom.test otherwise.
+func TestBinomialTest(t *testing.T) {
+	for _, c := range []struct {
+		wins, n   int
+		p, pvalue float64
+	}{
+		{5, 10, 0.5, 1},
+		{7, 10, 0.5, 0.34375},
+		{9, 10, 0.5, 0.021484375},
+		{10, 10, 0.5, 0.001953125},
+		{60, 100, 0.5, 0.05688793},
+		{2, 10, 0.3, 0.7331721},
+		{0, 0, 0.5, 1},
+	} {
+		if p := BinomialTest(c.wins, c.n, c.p); !near(p, c.pvalue, 5e-5) {
+			t.Errorf("BinomialTest(%d, %d, %g): expected %g, got %g", c.wins, c.n, c.p, c.pvalue, p)
+		}
+	}
+}
+
+func TestElo(t *testing.T) {
+	var elo = NewElo(32)
+	if e := elo.Expected(1, 2); e != 0.5 {
+		t.Errorf("expected 0.5 between new players, got %g", e)
+	}
+
+	elo.Update(1, 2)
+	if r := elo.Rating(1); r != 1516 {
+		t.Errorf("expected the winner to be rated 1516, got %g", r)
+	}
+	if r := elo.Rating(2); r != 1484 {
+		t.Errorf("expected the loser to be rated 1484, got %g", r)
+	}
+
+	// A difference of 400 points is odds of 10 to 1.
+	elo.ratings[3] = DefaultRating + 400
+	if e := elo.Expected(3, 4); !near(e, 10.0/11, 1e-12) {
+		t.Errorf("expected %g, got %g", 10.0/11, e)
+	}
+}
@@ -0,0 +1,171 @@
+package status
+
+import (
+	"logger"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// Exit codes of the commands.
+const (
+	// OK means the run completed without error.
+	OK = 0
+	// Failed means errors were reported during the run, but it completed.
+	Failed = 1
+	// Usage means the flags or arguments were invalid.
+	Usage = 2
+	// Setup means the run couldn't start or finish: opening the input,
+	// creating the output, connecting to the database, etc.
+	Setup = 3
+	// Strict means the run was stopped on its first error.
+	Strict = 4
+	// Interrupted means the run was stopped by a signal.
+	Interrupted = 130
+)
+
+// A Run counts the errors of a command by kind, the kind being the message
+// of the error, and decides of the exit code.
+type Run struct {
+	// errors is the total of counts, first in the struct so the atomic
+	// operations are aligned on every platform.
+	errors int64
+
+	// Strict makes the first error stop the run.
+	Strict bool
+
+	log      *logger.Logger
+	mu       sync.Mutex
+	counts   map[string]int
+	cleanups []func()
+	exits    []func(code int)
+}
+
+// New returns a run reporting its errors on the given logger.
+func New(log *logger.Logger) *Run {
+	return &Run{
+		log:    log,
+		counts: make(map[string]int),
+	}
+}
+
+// Error logs and counts an error. In strict mode, the run is stopped.
+func (r *Run) Error(msg string, data logger.M) {
+	r.log.Output(2, logger.ErrorLevel, msg, data)
+
+	atomic.AddInt64(&r.errors, 1)
+	r.mu.Lock()
+	r.counts[msg]++
+	r.mu.Unlock()
+
+	if r.Strict {
+		r.exit(Strict)
+	}
+}
+
+// Fatal logs an error and stops the run with the given exit code.
+func (r *Run) Fatal(code int, msg string, data logger.M) {
+	r.log.Output(2, logger.ErrorLevel, msg, data)
+
+	atomic.AddInt64(&r.errors, 1)
+	r.mu.Lock()
+	r.counts[msg]++
+	r.mu.Unlock()
+
+	r.exit(code)
+}
+
+// OnFailure registers a function called before the run is stopped by an
+// error, to remove incomplete outputs for example.
+func (r *Run) OnFailure(f func()) {
+	r.mu.Lock()
+	r.cleanups = append(r.cleanups, f)
+	r.mu.Unlock()
+}
+
+// OnExit registers a function called with the exit code before the run is
+// stopped, whatever the reason, to report on the run for example.
+func (r *Run) OnExit(f func(code int)) {
+	r.mu.Lock()
+	r.exits = append(r.exits, f)
+	r.mu.Unlock()
+}
+
+// Counts returns the number of errors reported so far, by kind.
+func (r *Run) Counts() map[string]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var counts = make(map[string]int, len(r.counts))
+	for k, n := range r.counts {
+		counts[k] = n
+	}
+	return counts
+}
+
+// Errors returns the number of errors reported so far. It doesn't lock the
+// run, so it can be called while a message is being logged, when drawing a
+// progress bar for example.
+func (r *Run) Errors() int {
+	return int(atomic.LoadInt64(&r.errors))
+}
+
+// Exit ends the run, with a nonzero exit code if errors were reported.
+func (r *Run) Exit() {
+	if r.Errors() != 0 {
+		r.summarize(2)
+		r.stop(Failed)
+	}
+	r.stop(OK)
+}
+
+func (r *Run) exit(code int) {
+	r.mu.Lock()
+	var cleanups = r.cleanups
+	r.cleanups = nil
+	r.mu.Unlock()
+
+	for i := len(cleanups) - 1; i >= 0; i-- {
+		cleanups[i]()
+	}
+
+	// Reached through Error or Fatal, the call site is two frames up.
+	r.summarize(3)
+	r.stop(code)
+}
+
+// stop calls the exit functions, then exits.
+func (r *Run) stop(code int) {
+	r.mu.Lock()
+	var exits = r.exits
+	r.exits = nil
+	r.mu.Unlock()
+
+	for i := len(exits) - 1; i >= 0; i-- {
+		exits[i](code)
+	}
+
+	os.Exit(code)
+}
+
+// summarize logs the number of errors of each kind. The counts are copied
+// first, as the run mustn't be locked while logging. The caller reported is
+// the one depth frames up from the caller of summarize, the call site of the
+// command ending the run.
+func (r *Run) summarize(depth int) {
+	var counts = r.Counts()
+
+	var kinds = make([]string, 0, len(counts))
+	for k := range counts {
+		kinds = append(kinds, k)
+	}
+	sort.Strings(kinds)
+
+	for _, k := range kinds {
+		r.log.Output(depth+1, logger.InfoLevel, "errors summary", logger.M{
+			"kind":  k,
+			"count": counts[k],
+		})
+	}
+}
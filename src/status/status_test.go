@@ -0,0 +1,135 @@
+package status
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"logger"
+	"os"
+	"os/exec"
+	"progress"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// TestExitWithBar runs Exit in a subprocess, with the logs written through a
+// progress bar showing the number of errors, as wtc does on a terminal.
+func TestExitWithBar(t *testing.T) {
+	if os.Getenv("STATUS_TEST_EXIT") == "1" {
+		var out bytes.Buffer
+		var log = logger.New(&out)
+		var run = New(log)
+
+		var bar = progress.NewBar(progress.New(), &out)
+		bar.Extra = func() string {
+			if run.Errors() != 0 {
+				return "errors"
+			}
+			return ""
+		}
+		log.SetOutput(bar)
+		run.OnExit(func(int) {
+			bar.Stop()
+		})
+
+		run.Error("failing", nil)
+		run.Exit()
+		return
+	}
+
+	var cmd = exec.Command(os.Args[0], "-test.run=^TestExitWithBar$")
+	cmd.Env = append(os.Environ(), "STATUS_TEST_EXIT=1")
+	err := cmd.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var done = make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		exit, ok := err.(*exec.ExitError)
+		if !ok {
+			t.Fatalf("expected exit code %d, got %v", Failed, err)
+		}
+		if code := exit.ExitCode(); code != Failed {
+			t.Fatalf("expected exit code %d, got %d", Failed, code)
+		}
+	case <-time.After(10 * time.Second):
+		cmd.Process.Kill()
+		t.Fatal("Exit deadlocked")
+	}
+}
+
+// TestSummaryCaller checks that the errors summary reports the call site of
+// the command ending the run, rather than the status package.
+func TestSummaryCaller(t *testing.T) {
+	if mode := os.Getenv("STATUS_TEST_CALLER"); mode != "" {
+		var log = logger.New(os.Stdout)
+		log.SetEncoder(logger.JSONEncoder{})
+		log.SetCaller(true)
+		var run = New(log)
+
+		// The summary must be reported at the same line as the error ending
+		// the run, or at the line of Exit.
+		switch mode {
+		case "exit":
+			run.Error("failing", nil)
+			var _, _, line, _ = runtime.Caller(0)
+			log.Info("expected", logger.M{"line": line + 2})
+			run.Exit()
+		case "fatal":
+			run.Fatal(Setup, "failing", nil)
+		case "strict":
+			run.Strict = true
+			run.Error("failing", nil)
+		}
+		return
+	}
+
+	for _, c := range []struct {
+		mode string
+		code int
+	}{
+		{"exit", Failed},
+		{"fatal", Setup},
+		{"strict", Strict},
+	} {
+		t.Run(c.mode, func(t *testing.T) {
+			var cmd = exec.Command(os.Args[0], "-test.run=^TestSummaryCaller$")
+			cmd.Env = append(os.Environ(), "STATUS_TEST_CALLER="+c.mode)
+			out, err := cmd.Output()
+			exit, ok := err.(*exec.ExitError)
+			if !ok || exit.ExitCode() != c.code {
+				t.Fatalf("expected exit code %d, got %v", c.code, err)
+			}
+
+			var expected, summary string
+			var scanner = bufio.NewScanner(bytes.NewReader(out))
+			for scanner.Scan() {
+				var fields logger.M
+				if json.Unmarshal(scanner.Bytes(), &fields) != nil {
+					continue
+				}
+				switch fields["msg"] {
+				case "expected":
+					expected = fmt.Sprintf("status/status_test.go:%v", fields["line"])
+				case "failing":
+					if c.mode != "exit" {
+						expected = fmt.Sprint(fields["caller"])
+					}
+				case "errors summary":
+					summary = fmt.Sprint(fields["caller"])
+				}
+			}
+			if expected == "" || summary != expected {
+				t.Errorf("expected the summary to be reported at %s, got %s", expected, summary)
+			}
+		})
+	}
+}
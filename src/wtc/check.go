@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"factions"
+	"flag"
+	"fmt"
+	"logger"
+	"status"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+var checkCommand = &Command{
+	Name:    "check",
+	Summary: "List the suspicious data of the database",
+	Flags:   flag.NewFlagSet("check", flag.ExitOnError),
+	Run:     runCheck,
+}
+
+func runCheck(ctx context.Context) {
+	db, err := sqlx.Connect("sqlite3", globals.Database)
+	if err != nil {
+		run.Fatal(status.Setup, "opening database", logger.M{
+			"path": globals.Database,
+			"err":  err,
+		})
+	}
+
+	// Find casters whose name in wrong
+	var casters = make([]string, 0, len(factions.Casters))
+	for caster := range factions.Casters {
+		casters = append(casters, caster)
+	}
+	query, args, _ := sqlx.In(`
+		select distinct
+			name,
+			round,
+			zone,
+			caster
+		from list
+		join player on player.id = list.player_id
+		join report on report.list_id = list.id
+		join game on game.id = report.game_id
+		join match on match.id = game.match_id
+		where caster not in (?)
+		order by caster
+	`, casters)
+
+	var typos []struct {
+		Name   string
+		Round  int
+		Zone   int
+		Caster string
+	}
+	err = db.Select(&typos, query, args...)
+	if err != nil {
+		run.Error("unable to get typoed casters", logger.M{
+			"err": err,
+		})
+	}
+
+	checkViolations.Add(float64(len(typos)), "unknown_caster")
+	for _, typo := range typos {
+		fmt.Println(typo)
+	}
+
+	// Find players whose lists don't agree on a faction
+	var mismatches []struct {
+		Name     string
+		Factions string
+	}
+	err = db.Select(&mismatches, `
+		select
+			player.name as name,
+			group_concat(distinct list.faction) as factions
+		from player
+		join list on list.player_id = player.id
+		where list.faction != ''
+		group by player.id
+		having count(distinct list.faction) > 1
+		order by player.name
+	`)
+	if err != nil {
+		run.Error("unable to get mismatched factions", logger.M{
+			"err": err,
+		})
+	}
+
+	checkViolations.Add(float64(len(mismatches)), "mismatched_player_faction")
+	for _, mismatch := range mismatches {
+		fmt.Println(mismatch)
+	}
+
+	// Find lists without faction
+	var unknowns []struct {
+		Name   string
+		Caster string
+	}
+	err = db.Select(&unknowns, `
+		select
+			player.name as name,
+			list.caster as caster
+		from list
+		join player on player.id = list.player_id
+		where list.faction is null or list.faction = ''
+		order by list.caster
+	`)
+	if err != nil {
+		run.Error("unable to get lists without faction", logger.M{
+			"err": err,
+		})
+	}
+
+	checkViolations.Add(float64(len(unknowns)), "list_without_faction")
+	for _, unknown := range unknowns {
+		fmt.Println(unknown)
+	}
+
+	// Find teams whose country is unknown
+	var teams []string
+	err = db.Select(&teams, `
+		select name
+		from team
+		where country_code is null or country_code = ''
+		order by name
+	`)
+	if err != nil {
+		run.Error("unable to get teams without country", logger.M{
+			"err": err,
+		})
+	}
+
+	checkViolations.Add(float64(len(teams)), "team_without_country")
+	for _, team := range teams {
+		fmt.Println(team)
+	}
+}
@@ -0,0 +1,422 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"country"
+	"fetch"
+	"logger"
+	"status"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	// URLTemplate is the default address of the pages of the rounds, %d
+	// being the round.
+	URLTemplate = "http://wmh-wtc.com/?round=%d"
+	Rounds      = 6
+)
+
+type (
+	Page struct {
+		Round int
+		Body  io.Reader
+	}
+
+	MatchNode struct {
+		Round int
+		Root  *html.Node
+	}
+
+	crawlOptions struct {
+		Watch     bool
+		Interval  time.Duration
+		Timeout   time.Duration
+		Retries   int
+		Rate      time.Duration
+		UserAgent string
+		Robots    bool
+		Workers   int
+		URL       string
+	}
+)
+
+var (
+	crawlCommand = &Command{
+		Name:    "crawl",
+		Summary: "Retrieve the matches from the WTC website and write them in the output file",
+		Flags:   flag.NewFlagSet("crawl", flag.ExitOnError),
+		Run:     runCrawl,
+	}
+	crawlOpts crawlOptions
+)
+
+func init() {
+	crawlOpts.register(crawlCommand.Flags)
+}
+
+func (o *crawlOptions) register(fs *flag.FlagSet) {
+	fs.BoolVar(&o.Watch, "watch", false, "poll the rounds and only emit new or updated matches")
+	fs.DurationVar(&o.Interval, "interval", time.Minute, "polling interval of the watch mode")
+	fs.DurationVar(&o.Timeout, "timeout", 30*time.Second, "timeout of a request")
+	fs.IntVar(&o.Retries, "retries", 3, "number of retries of a failed request")
+	fs.DurationVar(&o.Rate, "rate", time.Second, "minimum delay between two requests")
+	fs.StringVar(&o.UserAgent, "user-agent", "wtc-crawler (+https://github.com/elwinar/wtc)", "user agent of the requests")
+	fs.BoolVar(&o.Robots, "robots", true, "respect the robots.txt of the site")
+	fs.IntVar(&o.Workers, "workers", 4, "number of rounds retrieved concurrently")
+	fs.StringVar(&o.URL, "url", URLTemplate, "address of the pages of the rounds, %d being the round")
+}
+
+func runCrawl(ctx context.Context) {
+	// The watch mode doesn't end, its matches are written as they come.
+	var output = createOutput
+	if crawlOpts.Watch {
+		output = streamOutput
+	}
+	out, commit := output()
+
+	var encoder = json.NewEncoder(out)
+	for match := range crawlMatches(ctx, crawlOpts) {
+		writeMatch(encoder, match)
+	}
+
+	if ctx.Err() != nil {
+		if !crawlOpts.Watch {
+			run.Fatal(status.Interrupted, "interrupted", nil)
+		}
+		// The matches emitted so far are complete, keep them.
+		log.Info("interrupted", nil)
+	}
+
+	commit()
+}
+
+// crawlMatches retrieves the matches of every round. In watch mode, the
+// rounds are polled until the context is done, and only the new or updated
+// matches are sent.
+func crawlMatches(ctx context.Context, opts crawlOptions) <-chan Match {
+	var client = fetch.New(opts.Timeout, opts.UserAgent)
+	client.Retries = opts.Retries
+	client.Interval = opts.Rate
+	client.Robots = opts.Robots
+	client.Observe = observeFetch
+
+	var matches = make(chan Match)
+	go func() {
+		defer close(matches)
+
+		var seen = make(map[string]Match)
+		for ctx.Err() == nil {
+			var missing []int
+			var stop = tracker.Start("crawl")
+			tracker.Expect("pages", Rounds)
+			for match := range crawl(ctx, client, opts.URL, opts.Workers, &missing) {
+				if opts.Watch {
+					var key = fmt.Sprintf("%d/%s", match.Round, match.Zone)
+					previous, found := seen[key]
+					seen[key] = match
+
+					switch {
+					case !found:
+						match.Status = StatusNew
+					case !reflect.DeepEqual(previous, match):
+						match.Status = StatusUpdated
+					default:
+						continue
+					}
+				}
+
+				select {
+				case matches <- match:
+					matchesEmitted.Inc()
+				case <-ctx.Done():
+				}
+			}
+			stop()
+
+			if len(missing) != 0 && ctx.Err() == nil {
+				log.Error("missing rounds", logger.M{
+					"rounds": missing,
+				})
+			}
+
+			if !opts.Watch {
+				return
+			}
+
+			log.Info("waiting for next poll", logger.M{
+				"interval": opts.Interval,
+			})
+			select {
+			case <-time.After(opts.Interval):
+			case <-ctx.Done():
+			}
+		}
+	}()
+
+	return matches
+}
+
+// crawl retrieves every round from the pages of the URL template and extracts
+// their matches, using a pool of workers. The matches are sent ordered by
+// round then zone, whatever the order in which the rounds are retrieved. The
+// rounds which couldn't be retrieved are added to missing, which is complete
+// once the returned channel is closed.
+func crawl(ctx context.Context, client *fetch.Client, template string, workers int, missing *[]int) <-chan Match {
+	var rounds = make(chan int)
+	go func() {
+		defer close(rounds)
+		for i := 1; i <= Rounds; i++ {
+			select {
+			case rounds <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	type result struct {
+		round   int
+		matches []Match
+		err     error
+	}
+
+	var results = make(chan result)
+	var done = make(chan struct{})
+	var n = workers
+	if n < 1 {
+		n = 1
+	}
+	for w := 0; w < n; w++ {
+		go func() {
+			defer func() {
+				done <- struct{}{}
+			}()
+			for round := range rounds {
+				matches, err := crawlRound(ctx, client, template, round)
+				select {
+				case results <- result{
+					round:   round,
+					matches: matches,
+					err:     err,
+				}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		for w := 0; w < n; w++ {
+			<-done
+		}
+		close(results)
+	}()
+
+	var matches = make(chan Match)
+	go func() {
+		// Hold the results until all the previous rounds are sent.
+		var pending = make(map[int]result)
+		var next = 1
+		for r := range results {
+			pending[r.round] = r
+			for {
+				r, found := pending[next]
+				if !found {
+					break
+				}
+				delete(pending, next)
+				next++
+
+				if r.err != nil {
+					*missing = append(*missing, r.round)
+					continue
+				}
+				for _, match := range r.matches {
+					select {
+					case matches <- match:
+					case <-ctx.Done():
+					}
+				}
+			}
+		}
+		close(matches)
+	}()
+
+	return matches
+}
+
+// crawlRound retrieves a round and extracts its matches, ordered by zone.
+func crawlRound(ctx context.Context, client *fetch.Client, template string, round int) ([]Match, error) {
+	var URL = fmt.Sprintf(template, round)
+	log.Info("retrieving page", logger.M{
+		"round": round,
+		"url":   URL,
+	})
+	body, err := client.Get(ctx, URL)
+	if err != nil && ctx.Err() != nil {
+		return nil, err
+	}
+	if err != nil {
+		run.Error("retrieving page", logger.M{
+			"round": round,
+			"err":   err,
+		})
+		return nil, err
+	}
+	tracker.Add("pages", 1)
+
+	var page = Page{
+		Round: round,
+		Body:  bytes.NewReader(body),
+	}
+
+	log.Info("parsing page", logger.M{
+		"round": page.Round,
+	})
+	root, err := html.Parse(page.Body)
+	if err != nil {
+		parseFailures.Inc("page")
+		run.Error("parsing page", logger.M{
+			"round": page.Round,
+			"err":   err,
+		})
+		return nil, err
+	}
+
+	var matches []Match
+	for _, node := range walk(root, nil) {
+		matches = append(matches, extract(MatchNode{
+			Round: page.Round,
+			Root:  node,
+		}))
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return zoneLess(matches[i].Zone, matches[j].Zone)
+	})
+
+	tracker.Add("matches", len(matches))
+
+	return matches, nil
+}
+
+// extract reads a match from its node.
+func extract(node MatchNode) Match {
+	var match = Match{
+		Round: node.Round,
+		Zone:  strings.TrimSpace(node.Root.FirstChild.LastChild.FirstChild.Data),
+	}
+
+	log.Debug("extracting match", logger.M{
+		"round": node.Round,
+		"zone":  match.Zone,
+	})
+
+	for i, teamNode := range []*html.Node{
+		node.Root.FirstChild.NextSibling,
+		node.Root.FirstChild.NextSibling.NextSibling.NextSibling,
+	} {
+		match.Teams[i] = strings.TrimSpace(teamNode.FirstChild.LastChild.FirstChild.Data[len("Team"):])
+		match.Countries[i] = findCountry(teamNode)
+	}
+
+	var g int
+	for gameNode := node.Root.LastChild.FirstChild; gameNode != nil; gameNode = gameNode.NextSibling {
+		var game = Game{
+			Players: [2]string{
+				strings.TrimSpace(gameNode.FirstChild.FirstChild.Data),
+				strings.TrimSpace(gameNode.LastChild.FirstChild.Data),
+			},
+			Lists: [2]string{
+				strings.TrimSpace(gameNode.FirstChild.LastChild.FirstChild.Data),
+				strings.TrimSpace(gameNode.LastChild.LastChild.FirstChild.Data),
+			},
+		}
+
+		for _, attr := range gameNode.FirstChild.Attr {
+			if attr.Key != "class" {
+				continue
+			}
+
+			if strings.Contains(attr.Val, "winner") {
+				game.Winner = 0
+			} else {
+				game.Winner = 1
+			}
+			break
+		}
+
+		match.Games[g] = game
+		g++
+	}
+
+	return match
+}
+
+func walk(node *html.Node, nodes []*html.Node) []*html.Node {
+	var isPairing bool
+	for _, attr := range node.Attr {
+		if attr.Key == "class" && strings.Contains(attr.Val, "pairing-row") {
+			isPairing = true
+			break
+		}
+	}
+	if isPairing {
+		nodes = append(nodes, node)
+		return nodes
+	}
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		nodes = walk(child, nodes)
+	}
+
+	return nodes
+}
+
+// findCountry looks for the country markup or flag image of a team node, and
+// returns the code of the country if it can be resolved.
+func findCountry(node *html.Node) string {
+	if node.Type == html.ElementNode {
+		var candidates []string
+		for _, attr := range node.Attr {
+			switch {
+			case node.Data == "img" && (attr.Key == "alt" || attr.Key == "title" || attr.Key == "src"):
+				candidates = append(candidates, attr.Val)
+			case attr.Key == "class" && (strings.Contains(attr.Val, "flag") || strings.Contains(attr.Val, "country")):
+				for _, class := range strings.Fields(attr.Val) {
+					for _, prefix := range []string{"flag-icon-", "flag-", "country-"} {
+						class = strings.TrimPrefix(class, prefix)
+					}
+					candidates = append(candidates, class)
+				}
+				if node.FirstChild != nil && node.FirstChild.Type == html.TextNode {
+					candidates = append(candidates, node.FirstChild.Data)
+				}
+			}
+		}
+
+		for _, candidate := range candidates {
+			if c, ok := country.Lookup(candidate); ok {
+				return c.Code
+			}
+		}
+	}
+
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if code := findCountry(child); code != "" {
+			return code
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// page returns a page of the WTC website with a match for each zone, in the
+// given order.
+func page(round int, zones ...string) string {
+	var b strings.Builder
+	b.WriteString("<html><body>")
+	for _, zone := range zones {
+		fmt.Fprintf(&b, `<div class="pairing-row"><div><span>Zone</span><span>%s</span></div>`, zone)
+		fmt.Fprintf(&b, `<div><div><span>Team A%d</span></div></div><div></div><div><div><span>Team B%d</span></div></div><div>`, round, round)
+		for g := 0; g < 5; g++ {
+			fmt.Fprintf(&b, `<div><div class="winner">P%d%s%da<span>Haley 1</span></div><div>P%d%s%db<span>Vayl 1</span></div></div>`, round, zone, g, round, zone, g)
+		}
+		b.WriteString("</div></div>")
+	}
+	b.WriteString("</body></html>")
+	return b.String()
+}
+
+func TestCrawlOrder(t *testing.T) {
+	// The first rounds are the slowest, so the rounds are retrieved in
+	// about the reverse order.
+	var delays = map[int]time.Duration{
+		1: 80 * time.Millisecond,
+		2: 20 * time.Millisecond,
+		3: 60 * time.Millisecond,
+		4: 0,
+		5: 40 * time.Millisecond,
+		6: 10 * time.Millisecond,
+	}
+	var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		round, err := strconv.Atoi(r.URL.Query().Get("round"))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		time.Sleep(delays[round])
+		fmt.Fprint(w, page(round, "10", "2", "1"))
+	}))
+	defer server.Close()
+
+	var expected []string
+	for round := 1; round <= Rounds; round++ {
+		for _, zone := range []string{"1", "2", "10"} {
+			expected = append(expected, fmt.Sprintf("%d/%s A%d B%d", round, zone, round, round))
+		}
+	}
+
+	for _, workers := range []int{1, 3, Rounds} {
+		var got []string
+		for match := range crawlMatches(context.Background(), crawlOptions{
+			Timeout: time.Second,
+			Workers: workers,
+			URL:     server.URL + "/?round=%d",
+		}) {
+			got = append(got, fmt.Sprintf("%d/%s %s %s", match.Round, match.Zone, match.Teams[0], match.Teams[1]))
+		}
+
+		if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+			t.Errorf("%d workers: expected the matches\n%s\ngot\n%s", workers, strings.Join(expected, "\n"), strings.Join(got, "\n"))
+		}
+	}
+}
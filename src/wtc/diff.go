@@ -0,0 +1,331 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"jsonl"
+	"logger"
+	"sort"
+	"status"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type (
+	// Change is a difference between the two sets of matches. Changes of
+	// the match itself have no game number.
+	Change struct {
+		Kind   string  `json:"kind"`
+		Round  int     `json:"round"`
+		Zone   string  `json:"zone"`
+		Game   int     `json:"game,omitempty"`
+		Fields []Field `json:"fields,omitempty"`
+	}
+
+	Field struct {
+		Name string      `json:"name"`
+		Old  interface{} `json:"old"`
+		New  interface{} `json:"new"`
+	}
+
+	diffOptions struct {
+		Old    string
+		New    string
+		Format string
+	}
+)
+
+const (
+	Added   = "added"
+	Removed = "removed"
+	Changed = "changed"
+)
+
+var (
+	diffCommand = &Command{
+		Name:    "diff",
+		Summary: "Compare two crawler files or databases and write the added, removed and changed matches",
+		Flags:   flag.NewFlagSet("diff", flag.ExitOnError),
+		Run:     runDiff,
+	}
+	diffOpts diffOptions
+)
+
+func init() {
+	diffOpts.register(diffCommand.Flags)
+}
+
+func (o *diffOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.Old, "old", "", "old crawler file or database")
+	fs.StringVar(&o.New, "new", "", "new crawler file or database")
+	fs.StringVar(&o.Format, "format", "text", "output format (text or json)")
+}
+
+func runDiff(ctx context.Context) {
+	if diffOpts.Format != "text" && diffOpts.Format != "json" {
+		run.Fatal(status.Usage, "unknown format", logger.M{
+			"format": diffOpts.Format,
+		})
+	}
+
+	var sets [2]map[string]Match
+	for i, path := range []string{diffOpts.Old, diffOpts.New} {
+		matches, err := loadSet(path)
+		if err != nil {
+			run.Fatal(status.Setup, "loading matches", logger.M{
+				"path": path,
+				"err":  err,
+			})
+		}
+		sets[i] = matches
+	}
+
+	out, commit := createOutput()
+
+	var changes = diff(sets[0], sets[1])
+	var encoder = json.NewEncoder(out)
+	for _, change := range changes {
+		var err error
+		if diffOpts.Format == "json" {
+			err = encoder.Encode(change)
+		} else {
+			_, err = fmt.Fprintln(out, change)
+		}
+		if err != nil {
+			run.Fatal(status.Setup, "writing change", logger.M{
+				"err": err,
+			})
+		}
+	}
+	commit()
+
+	log.Info("compared matches", logger.M{
+		"old":     len(sets[0]),
+		"new":     len(sets[1]),
+		"changes": len(changes),
+	})
+}
+
+// diff compares the matches of both sets, matches being identified by their
+// round and zone, and games by their position in the match.
+func diff(before, after map[string]Match) []Change {
+	var all = make(map[string]Match)
+	var keys []string
+	for _, set := range []map[string]Match{before, after} {
+		for k, m := range set {
+			if _, found := all[k]; !found {
+				all[k] = m
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := all[keys[i]], all[keys[j]]
+		if a.Round != b.Round {
+			return a.Round < b.Round
+		}
+		return zoneLess(a.Zone, b.Zone)
+	})
+
+	var changes []Change
+	for _, k := range keys {
+		o, inOld := before[k]
+		n, inNew := after[k]
+
+		switch {
+		case !inNew:
+			for g := range o.Games {
+				changes = append(changes, Change{Kind: Removed, Round: o.Round, Zone: o.Zone, Game: g + 1, Fields: fields(o.Games[g], Game{}, false)})
+			}
+		case !inOld:
+			for g := range n.Games {
+				changes = append(changes, Change{Kind: Added, Round: n.Round, Zone: n.Zone, Game: g + 1, Fields: fields(Game{}, n.Games[g], false)})
+			}
+		default:
+			var teams []Field
+			for i := 0; i <= 1; i++ {
+				if o.Teams[i] != n.Teams[i] {
+					teams = append(teams, Field{Name: fmt.Sprintf("teams[%d]", i), Old: o.Teams[i], New: n.Teams[i]})
+				}
+			}
+			if len(teams) != 0 {
+				changes = append(changes, Change{Kind: Changed, Round: n.Round, Zone: n.Zone, Fields: teams})
+			}
+
+			for g := range n.Games {
+				if f := fields(o.Games[g], n.Games[g], true); len(f) != 0 {
+					changes = append(changes, Change{Kind: Changed, Round: n.Round, Zone: n.Zone, Game: g + 1, Fields: f})
+				}
+			}
+		}
+	}
+
+	return changes
+}
+
+// fields lists the fields of the games. Only the differing ones are kept if
+// asked to.
+func fields(before, after Game, differing bool) []Field {
+	var fields []Field
+	for i := 0; i <= 1; i++ {
+		fields = append(fields,
+			Field{Name: fmt.Sprintf("players[%d]", i), Old: before.Players[i], New: after.Players[i]},
+			Field{Name: fmt.Sprintf("lists[%d]", i), Old: before.Lists[i], New: after.Lists[i]},
+		)
+	}
+	fields = append(fields, Field{Name: "winner", Old: before.Winner, New: after.Winner})
+
+	if !differing {
+		return fields
+	}
+
+	var kept []Field
+	for _, f := range fields {
+		if f.Old != f.New {
+			kept = append(kept, f)
+		}
+	}
+	return kept
+}
+
+// String returns the human-readable form of the change.
+func (c Change) String() string {
+	var prefix = map[string]string{Added: "+", Removed: "-", Changed: "~"}[c.Kind]
+	var where = fmt.Sprintf("round %d zone %s", c.Round, c.Zone)
+	if c.Game != 0 {
+		where += fmt.Sprintf(" game %d", c.Game)
+	}
+
+	var parts []string
+	for _, f := range c.Fields {
+		switch c.Kind {
+		case Added:
+			parts = append(parts, fmt.Sprintf("%s=%v", f.Name, f.New))
+		case Removed:
+			parts = append(parts, fmt.Sprintf("%s=%v", f.Name, f.Old))
+		default:
+			parts = append(parts, fmt.Sprintf("%s: %q -> %q", f.Name, fmt.Sprint(f.Old), fmt.Sprint(f.New)))
+		}
+	}
+
+	return fmt.Sprintf("%s %s: %s", prefix, where, strings.Join(parts, ", "))
+}
+
+// loadSet reads the matches of a crawler file or of a database, by round and
+// zone.
+func loadSet(path string) (map[string]Match, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []Match
+	if bytes.HasPrefix(content, []byte("SQLite format 3\x00")) {
+		matches, err = loadDatabase(path)
+	} else {
+		matches, err = loadFile(bytes.NewReader(content))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var set = make(map[string]Match)
+	for _, m := range matches {
+		set[fmt.Sprintf("%d/%s", m.Round, m.Zone)] = m
+	}
+	return set, nil
+}
+
+func loadFile(in io.Reader) ([]Match, error) {
+	var matches []Match
+	var reader = jsonl.NewReader(in)
+	for {
+		var match Match
+		err := reader.Read(&match)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, match)
+	}
+	return matches, nil
+}
+
+// loadDatabase rebuilds the matches from the reports of a database, the
+// reports of a game being ordered like in the crawler file.
+func loadDatabase(path string) ([]Match, error) {
+	db, err := sqlx.Connect("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var reports []struct {
+		MatchID int    `db:"match_id"`
+		Round   int    `db:"round"`
+		Zone    string `db:"zone"`
+		GameID  int    `db:"game_id"`
+		Team    string `db:"team"`
+		Player  string `db:"player"`
+		Caster  string `db:"caster"`
+		Won     bool   `db:"won"`
+	}
+	err = db.Select(&reports, `
+		select
+			match.id as match_id,
+			match.round as round,
+			match.zone as zone,
+			game.id as game_id,
+			trim(team.country || ' ' || team.name) as team,
+			player.name as player,
+			list.caster as caster,
+			report.won as won
+		from report
+		join game on game.id = report.game_id
+		join match on match.id = game.match_id
+		join list on list.id = report.list_id
+		join player on player.id = list.player_id
+		join team on team.id = player.team_id
+		order by match.id, game.id, report.id
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []Match
+	var matchID, gameID, g, side int
+	for _, r := range reports {
+		if r.MatchID != matchID || len(matches) == 0 {
+			matches = append(matches, Match{Round: r.Round, Zone: r.Zone})
+			matchID, gameID, g = r.MatchID, r.GameID, 0
+			side = 0
+		} else if r.GameID != gameID {
+			gameID, g, side = r.GameID, g+1, 0
+		}
+		if g >= len(Match{}.Games) || side > 1 {
+			continue
+		}
+
+		var m = &matches[len(matches)-1]
+		if m.Teams[side] == "" {
+			m.Teams[side] = r.Team
+		}
+		m.Games[g].Players[side] = r.Player
+		m.Games[g].Lists[side] = r.Caster
+		if r.Won {
+			m.Games[g].Winner = side
+		}
+		side++
+	}
+
+	return matches, nil
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"country"
+	"encoding/csv"
+	"factions"
+	"flag"
+	"fmt"
+	"logger"
+	"os"
+	"path/filepath"
+	"status"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type (
+	// ExportRow is a denormalized report, as exported.
+	ExportRow struct {
+		Round          int    `db:"round"`
+		Zone           string `db:"zone"`
+		Team           string `db:"team"`
+		Country        string `db:"country"`
+		Player         string `db:"player"`
+		Faction        string `db:"faction"`
+		Caster         string `db:"caster"`
+		Opponent       string `db:"opponent"`
+		OpponentCaster string `db:"opponent_caster"`
+		Won            bool   `db:"won"`
+	}
+
+	exportOptions struct {
+		Matches bool
+		Tables  string
+		Columns string
+		Round   int
+		Faction string
+	}
+)
+
+// ExportColumns is the list of the available columns, in their default
+// order.
+var ExportColumns = []string{
+	"round",
+	"zone",
+	"team",
+	"country",
+	"player",
+	"faction",
+	"caster",
+	"opponent",
+	"opponent_caster",
+	"result",
+}
+
+var (
+	exportCommand = &Command{
+		Name:    "export",
+		Summary: "Write the reports of the database, or of the input file, as CSV",
+		Flags:   flag.NewFlagSet("export", flag.ExitOnError),
+		Run:     runExport,
+	}
+	exportOpts exportOptions
+)
+
+func init() {
+	exportOpts.register(exportCommand.Flags)
+}
+
+func (o *exportOptions) register(fs *flag.FlagSet) {
+	fs.BoolVar(&o.Matches, "matches", false, "export the matches of the input file instead of the database")
+	fs.StringVar(&o.Tables, "tables", "", "directory to dump each table of the database into")
+	fs.StringVar(&o.Columns, "columns", strings.Join(ExportColumns, ","), "comma-separated list of exported columns")
+	fs.IntVar(&o.Round, "round", 0, "only export the given round")
+	fs.StringVar(&o.Faction, "faction", "", "only export the reports of the given faction")
+}
+
+func runExport(ctx context.Context) {
+	var cols = strings.Split(exportOpts.Columns, ",")
+	for _, col := range cols {
+		if _, err := field(ExportRow{}, col); err != nil {
+			run.Fatal(status.Usage, "parsing columns", logger.M{
+				"column": col,
+				"err":    err,
+			})
+		}
+	}
+
+	var rows []ExportRow
+	if exportOpts.Matches {
+		for match := range readMatches(ctx) {
+			rows = append(rows, denormalize(match)...)
+		}
+		if ctx.Err() != nil {
+			run.Fatal(status.Interrupted, "interrupted", nil)
+		}
+	} else {
+		db, err := sqlx.Connect("sqlite3", "file:"+globals.Database+"?mode=ro")
+		if err != nil {
+			run.Fatal(status.Setup, "opening database", logger.M{
+				"path": globals.Database,
+				"err":  err,
+			})
+		}
+		defer db.Close()
+
+		err = db.Select(&rows, `
+			select
+				match.round as round,
+				match.zone as zone,
+				team.name as team,
+				team.country as country,
+				player.name as player,
+				list.faction as faction,
+				list.caster as caster,
+				opponent.name as opponent,
+				opponent_list.caster as opponent_caster,
+				report.won as won
+			from report
+			join list on list.id = report.list_id
+			join player on player.id = list.player_id
+			join team on team.id = player.team_id
+			join game on game.id = report.game_id
+			join match on match.id = game.match_id
+			join report as opponent_report on opponent_report.game_id = report.game_id and opponent_report.id != report.id
+			join list as opponent_list on opponent_list.id = opponent_report.list_id
+			join player as opponent on opponent.id = opponent_list.player_id
+			order by match.round, match.zone, game.id, report.id
+		`)
+		if err != nil {
+			run.Fatal(status.Setup, "reading reports", logger.M{
+				"err": err,
+			})
+		}
+
+		if exportOpts.Tables != "" {
+			for _, table := range []string{"team", "player", "list", "match", "game", "report"} {
+				var path = filepath.Join(exportOpts.Tables, table+".csv")
+				log.Info("dumping table", logger.M{
+					"table": table,
+					"path":  path,
+				})
+				err = dump(db, table, path)
+				if err != nil {
+					run.Fatal(status.Setup, "dumping table", logger.M{
+						"table": table,
+						"path":  path,
+						"err":   err,
+					})
+				}
+			}
+		}
+	}
+
+	out, commit := createOutput()
+
+	var writer = csv.NewWriter(out)
+	_ = writer.Write(cols)
+	for _, row := range rows {
+		if exportOpts.Round != 0 && row.Round != exportOpts.Round {
+			continue
+		}
+
+		if exportOpts.Faction != "" && row.Faction != exportOpts.Faction {
+			continue
+		}
+
+		var record = make([]string, len(cols))
+		for i, col := range cols {
+			record[i], _ = field(row, col)
+		}
+		_ = writer.Write(record)
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		run.Fatal(status.Setup, "writing reports", logger.M{
+			"path": globals.Output,
+			"err":  err,
+		})
+	}
+	commit()
+}
+
+// denormalize returns the reports of both players of each game of the match.
+func denormalize(match Match) []ExportRow {
+	var rows []ExportRow
+	for _, game := range match.Games {
+		for i := 0; i <= 1; i++ {
+			var c, team, _ = country.ParseTeam(match.Teams[i], match.Countries[i])
+			rows = append(rows, ExportRow{
+				Round:          match.Round,
+				Zone:           match.Zone,
+				Team:           team,
+				Country:        c.Name,
+				Player:         game.Players[i],
+				Faction:        factions.Casters[game.Lists[i]],
+				Caster:         game.Lists[i],
+				Opponent:       game.Players[1-i],
+				OpponentCaster: game.Lists[1-i],
+				Won:            game.Winner == i,
+			})
+		}
+	}
+	return rows
+}
+
+// field returns the value of the given column of the row.
+func field(r ExportRow, col string) (string, error) {
+	switch col {
+	case "round":
+		return strconv.Itoa(r.Round), nil
+	case "zone":
+		return r.Zone, nil
+	case "team":
+		return r.Team, nil
+	case "country":
+		return r.Country, nil
+	case "player":
+		return r.Player, nil
+	case "faction":
+		return r.Faction, nil
+	case "caster":
+		return r.Caster, nil
+	case "opponent":
+		return r.Opponent, nil
+	case "opponent_caster":
+		return r.OpponentCaster, nil
+	case "result":
+		if r.Won {
+			return "won", nil
+		}
+		return "lost", nil
+	}
+	return "", fmt.Errorf("unknown column %q", col)
+}
+
+// dump writes the whole content of a table in a CSV file.
+func dump(db *sqlx.DB, table, path string) error {
+	rows, err := db.Queryx("select * from " + table)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var writer = csv.NewWriter(file)
+	_ = writer.Write(cols)
+	for rows.Next() {
+		values, err := rows.SliceScan()
+		if err != nil {
+			return err
+		}
+
+		var record = make([]string, len(values))
+		for i, v := range values {
+			switch v := v.(type) {
+			case nil:
+			case []byte:
+				record[i] = string(v)
+			default:
+				record[i] = fmt.Sprint(v)
+			}
+		}
+		_ = writer.Write(record)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
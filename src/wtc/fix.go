@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"status"
+)
+
+var fixCommand = &Command{
+	Name:    "fix",
+	Summary: "Correct the known mistakes of the matches of the input file",
+	Flags:   flag.NewFlagSet("fix", flag.ExitOnError),
+	Run:     runFix,
+}
+
+func runFix(ctx context.Context) {
+	out, commit := createOutput()
+
+	var encoder = json.NewEncoder(out)
+	for match := range fixMatches(ctx, readMatches(ctx)) {
+		writeMatch(encoder, match)
+	}
+
+	if ctx.Err() != nil {
+		run.Fatal(status.Interrupted, "interrupted", nil)
+	}
+
+	commit()
+}
+
+// fixMatches corrects the matches as they come.
+func fixMatches(ctx context.Context, matches <-chan Match) <-chan Match {
+	var fixedMatches = make(chan Match)
+	go func() {
+		defer close(fixedMatches)
+		defer tracker.Start("fix")()
+		for match := range matches {
+			match = fix(match)
+			tracker.Add("fixed", 1)
+
+			select {
+			case fixedMatches <- match:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return fixedMatches
+}
+
+// fix corrects the known mistakes of a match.
+func fix(match Match) Match {
+	for g, game := range match.Games {
+		for l := 0; l <= 1; l++ {
+			if game.Lists[l] == "vHarkevich 1" {
+				match.Games[g].Lists[l] = "Harkevich 1"
+			}
+		}
+	}
+
+	return match
+}
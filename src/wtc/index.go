@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"country"
+	"encoding/json"
+	"factions"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"logger"
+	"net/http"
+	"status"
+	"time"
+)
+
+type (
+	// Document is a game, as indexed.
+	Document struct {
+		Round int             `json:"round"`
+		Zone  string          `json:"zone"`
+		Game  int             `json:"game"`
+		Sides [2]DocumentSide `json:"sides"`
+	}
+
+	DocumentSide struct {
+		Team   DocumentTeam   `json:"team"`
+		Player DocumentPlayer `json:"player"`
+		List   DocumentList   `json:"list"`
+		Won    bool           `json:"won"`
+	}
+
+	DocumentTeam struct {
+		Name        string `json:"name"`
+		Country     string `json:"country"`
+		CountryCode string `json:"country_code"`
+	}
+
+	DocumentPlayer struct {
+		Name string `json:"name"`
+	}
+
+	DocumentList struct {
+		Caster  string `json:"caster"`
+		Faction string `json:"faction"`
+	}
+
+	// Action is the line preceding each document in the bulk format.
+	Action struct {
+		Index struct {
+			Index string `json:"_index"`
+			ID    string `json:"_id"`
+		} `json:"index"`
+	}
+
+	indexOptions struct {
+		URL     string
+		Index   string
+		Batch   int
+		Retries int
+		Timeout time.Duration
+	}
+)
+
+var (
+	indexCommand = &Command{
+		Name:    "index",
+		Summary: "Write the games of the input file in the bulk format of Elasticsearch, or push them to an endpoint",
+		Flags:   flag.NewFlagSet("index", flag.ExitOnError),
+		Run:     runIndex,
+	}
+	indexOpts indexOptions
+
+	// retryBackoff is the wait before the first retry of a bulk request,
+	// doubled on each retry.
+	retryBackoff = time.Second
+)
+
+func init() {
+	indexOpts.register(indexCommand.Flags)
+}
+
+func (o *indexOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.URL, "url", "", "bulk endpoint to push the documents to, instead of writing them in the output file")
+	fs.StringVar(&o.Index, "index", "wtc", "index name")
+	fs.IntVar(&o.Batch, "batch", 500, "number of documents per bulk request")
+	fs.IntVar(&o.Retries, "retries", 3, "number of retries of a failed bulk request")
+	fs.DurationVar(&o.Timeout, "timeout", 30*time.Second, "timeout of a bulk request")
+}
+
+func runIndex(ctx context.Context) {
+	var out io.Writer
+	var commit = func() {}
+	if indexOpts.URL == "" {
+		out, commit = createOutput()
+	}
+
+	var client = &http.Client{
+		Timeout: indexOpts.Timeout,
+	}
+
+	var buf bytes.Buffer
+	var count int
+	var flush = func() {
+		if count == 0 {
+			return
+		}
+
+		if indexOpts.URL == "" {
+			_, err := buf.WriteTo(out)
+			if err != nil {
+				run.Fatal(status.Setup, "writing documents", logger.M{
+					"err": err,
+				})
+			}
+		} else {
+			log.Info("pushing documents", logger.M{
+				"url":   indexOpts.URL,
+				"count": count,
+			})
+			err := push(client, indexOpts.URL, buf.Bytes())
+			if err != nil {
+				run.Fatal(status.Setup, "pushing documents", logger.M{
+					"url":   indexOpts.URL,
+					"count": count,
+					"err":   err,
+				})
+			}
+		}
+
+		buf.Reset()
+		count = 0
+	}
+
+	var encoder = json.NewEncoder(&buf)
+	for match := range readMatches(ctx) {
+		for g, game := range match.Games {
+			encode(encoder, match, g, game)
+			count++
+
+			if count >= indexOpts.Batch {
+				flush()
+			}
+		}
+	}
+	if ctx.Err() != nil {
+		run.Fatal(status.Interrupted, "interrupted", nil)
+	}
+
+	flush()
+	commit()
+}
+
+// encode writes the action and the document of the given game of the match,
+// in the bulk format.
+func encode(encoder *json.Encoder, match Match, g int, game Game) {
+	var action Action
+	action.Index.Index = indexOpts.Index
+	action.Index.ID = fmt.Sprintf("%d-%s-%d", match.Round, match.Zone, g+1)
+	_ = encoder.Encode(action)
+	_ = encoder.Encode(document(match, g, game))
+}
+
+// document returns the document of the given game of the match.
+func document(match Match, g int, game Game) Document {
+	var doc = Document{
+		Round: match.Round,
+		Zone:  match.Zone,
+		Game:  g + 1,
+	}
+
+	for i := 0; i <= 1; i++ {
+		var c, name, _ = country.ParseTeam(match.Teams[i], match.Countries[i])
+		doc.Sides[i] = DocumentSide{
+			Team: DocumentTeam{
+				Name:        name,
+				Country:     c.Name,
+				CountryCode: c.Code,
+			},
+			Player: DocumentPlayer{
+				Name: game.Players[i],
+			},
+			List: DocumentList{
+				Caster:  game.Lists[i],
+				Faction: factions.Casters[game.Lists[i]],
+			},
+			Won: game.Winner == i,
+		}
+	}
+
+	return doc
+}
+
+// push sends a bulk request to the endpoint. Network errors, server errors
+// and throttling are retried with an exponential backoff.
+func push(client *http.Client, url string, body []byte) error {
+	var backoff = retryBackoff
+	var err error
+	for attempt := 0; attempt <= indexOpts.Retries; attempt++ {
+		if attempt > 0 {
+			log.Info("retrying bulk request", logger.M{
+				"attempt": attempt,
+				"backoff": backoff,
+				"err":     err,
+			})
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+		var res *http.Response
+		res, err = client.Post(url, "application/x-ndjson", bytes.NewReader(body))
+		if err != nil {
+			continue
+		}
+
+		var result struct {
+			Errors bool `json:"errors"`
+		}
+		var raw, _ = ioutil.ReadAll(res.Body)
+		res.Body.Close()
+
+		if res.StatusCode >= 500 || res.StatusCode == http.StatusTooManyRequests {
+			err = fmt.Errorf("unexpected status %s", res.Status)
+			continue
+		}
+
+		if res.StatusCode >= 300 {
+			return fmt.Errorf("unexpected status %s: %s", res.Status, raw)
+		}
+
+		// The bulk API answers 200 even if some documents were rejected.
+		_ = json.Unmarshal(raw, &result)
+		if result.Errors {
+			return fmt.Errorf("rejected documents: %s", raw)
+		}
+
+		return nil
+	}
+
+	return err
+}
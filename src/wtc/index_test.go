@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// bulkServer answers the bulk requests with the given statuses in turn, then
+// with 200, and records the requests.
+type bulkServer struct {
+	statuses []int
+	bodies   [][]byte
+	types    []string
+}
+
+func (s *bulkServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body, _ = ioutil.ReadAll(r.Body)
+	s.bodies = append(s.bodies, body)
+	s.types = append(s.types, r.Header.Get("Content-Type"))
+
+	if len(s.bodies) <= len(s.statuses) {
+		w.WriteHeader(s.statuses[len(s.bodies)-1])
+		return
+	}
+	w.Write([]byte(`{"errors":false}`))
+}
+
+func TestPushRetries(t *testing.T) {
+	retryBackoff = time.Millisecond
+
+	var match = Match{
+		Round:     2,
+		Zone:      "12",
+		Teams:     [2]string{"France Blue", "England Lions"},
+		Countries: [2]string{"FR", "GB-ENG"},
+	}
+	match.Games[0] = Game{
+		Players: [2]string{"Alice", "Bob"},
+		Lists:   [2]string{"Haley 2", "Lylyth 1"},
+		Winner:  1,
+	}
+	var buf bytes.Buffer
+	encode(json.NewEncoder(&buf), match, 0, match.Games[0])
+
+	var handler = &bulkServer{
+		statuses: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusBadGateway},
+	}
+	var server = httptest.NewServer(handler)
+	defer server.Close()
+
+	err := push(server.Client(), server.URL, buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(handler.bodies) != 4 {
+		t.Fatalf("expected 4 requests, got %d", len(handler.bodies))
+	}
+	for i, body := range handler.bodies {
+		if !bytes.Equal(body, handler.bodies[0]) {
+			t.Errorf("request %d: the body differs from the first one: %q", i, body)
+		}
+		if handler.types[i] != "application/x-ndjson" {
+			t.Errorf("request %d: expected application/x-ndjson, got %q", i, handler.types[i])
+		}
+	}
+
+	var body = handler.bodies[0]
+	if !bytes.HasSuffix(body, []byte("\n")) {
+		t.Errorf("the body doesn't end with a newline: %q", body)
+	}
+	var lines []string
+	var scanner = bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if len(lines) != 2 {
+		t.Fatalf("expected an action and a document, got %q", lines)
+	}
+
+	var action Action
+	err = json.Unmarshal([]byte(lines[0]), &action)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if action.Index.Index != "wtc" || action.Index.ID != "2-12-1" {
+		t.Errorf("unexpected action %s", lines[0])
+	}
+
+	var doc Document
+	err = json.Unmarshal([]byte(lines[1]), &doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expected = [2]DocumentSide{
+		{
+			Team:   DocumentTeam{Name: "Blue", Country: "France", CountryCode: "FR"},
+			Player: DocumentPlayer{Name: "Alice"},
+			List:   DocumentList{Caster: "Haley 2", Faction: "cygnar"},
+		},
+		{
+			Team:   DocumentTeam{Name: "Lions", Country: "England", CountryCode: "GB-ENG"},
+			Player: DocumentPlayer{Name: "Bob"},
+			List:   DocumentList{Caster: "Lylyth 1", Faction: "everblight"},
+			Won:    true,
+		},
+	}
+	if doc.Round != 2 || doc.Zone != "12" || doc.Game != 1 || doc.Sides != expected {
+		t.Errorf("unexpected document %s", lines[1])
+	}
+}
+
+func TestPushGivesUp(t *testing.T) {
+	retryBackoff = time.Millisecond
+
+	for _, c := range []struct {
+		name     string
+		statuses []int
+		requests int
+	}{
+		{
+			name:     "server errors",
+			statuses: []int{500, 500, 500, 500, 500},
+			requests: indexOpts.Retries + 1,
+		},
+		{
+			name:     "client error",
+			statuses: []int{http.StatusBadRequest},
+			requests: 1,
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			var handler = &bulkServer{statuses: c.statuses}
+			var server = httptest.NewServer(handler)
+			defer server.Close()
+
+			err := push(server.Client(), server.URL, []byte("{}\n"))
+			if err == nil || !strings.Contains(err.Error(), "unexpected status") {
+				t.Errorf("expected an unexpected status, got %v", err)
+			}
+			if len(handler.bodies) != c.requests {
+				t.Errorf("expected %d requests, got %d", c.requests, len(handler.bodies))
+			}
+		})
+	}
+}
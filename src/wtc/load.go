@@ -0,0 +1,462 @@
+package main
+
+import (
+	"atomicfile"
+	"context"
+	"country"
+	"database/sql"
+	"factions"
+	"flag"
+	"identity"
+	"logger"
+	"status"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type (
+	Team struct {
+		ID          int
+		Name        string
+		Country     string
+		CountryCode string
+		Players     [5]string
+	}
+
+	Player struct {
+		ID         int
+		Name       string
+		TeamID     int
+		IdentityID int
+		Lists      [2]string
+	}
+
+	List struct {
+		ID       int
+		Caster   string
+		Faction  string
+		PlayerID int
+	}
+
+	loadOptions struct {
+		Identities string
+		Live       bool
+	}
+)
+
+var (
+	loadCommand = &Command{
+		Name:    "load",
+		Summary: "Insert the matches of the input file into the database",
+		Flags:   flag.NewFlagSet("load", flag.ExitOnError),
+		Run:     runLoad,
+	}
+	loadOpts loadOptions
+)
+
+func init() {
+	loadOpts.register(loadCommand.Flags)
+}
+
+func (o *loadOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.Identities, "identities", "", "player identities file")
+	fs.BoolVar(&o.Live, "live", false, "write the matches directly into the database as they come")
+}
+
+func runLoad(ctx context.Context) {
+	loadMatches(ctx, readMatches(ctx), loadOpts)
+}
+
+// loadMatches inserts the matches into the database as they come.
+func loadMatches(ctx context.Context, matches <-chan Match, opts loadOptions) {
+	defer tracker.Start("load")()
+
+	// Unless the matches are streamed live, the database is built in a
+	// temporary file moved in place once complete.
+	var path = globals.Database
+	var tmp *atomicfile.File
+	if !opts.Live {
+		var err error
+		tmp, err = atomicfile.Create(globals.Database)
+		if err != nil {
+			run.Fatal(status.Setup, "creating database", logger.M{
+				"path": globals.Database,
+				"err":  err,
+			})
+		}
+		tmp.Close()
+		path = tmp.Name()
+		run.OnFailure(func() {
+			tmp.Abort()
+		})
+	}
+
+	db, err := sqlx.Connect("sqlite3", path)
+	if err != nil {
+		run.Fatal(status.Setup, "opening database", logger.M{
+			"path": globals.Database,
+			"err":  err,
+		})
+	}
+
+	var registry = identity.New()
+	if opts.Identities != "" {
+		registry, err = identity.Load(opts.Identities)
+		if err != nil {
+			run.Fatal(status.Setup, "loading identities", logger.M{
+				"path": opts.Identities,
+				"err":  err,
+			})
+		}
+	}
+
+	for _, query := range []string{
+		"create table if not exists team ( id integer primary key, name varchar(50), country varchar(50), country_code varchar(6) )",
+		"create table if not exists player ( id integer primary key, name varchar(50), faction varchar(50), team_id integer, identity_id integer )",
+		"create table if not exists list ( id integer primary key, caster varchar(50), faction varchar(50), player_id integer )",
+		"create table if not exists match ( id integer primary key, round integer, zone integer )",
+		"create table if not exists game ( id integer primary key, match_id integer )",
+		"create table if not exists report ( id integer primary key, game_id integer, list_id integer, won boolean )",
+	} {
+		_, err = db.Exec(query)
+		if err != nil {
+			run.Fatal(status.Setup, "creating schema", logger.M{
+				"path": globals.Database,
+				"err":  err,
+			})
+		}
+	}
+
+	// The teams are keyed by country code and name, the players and their
+	// lists by identity.
+	var teams = make(map[string]int)
+	var players = make(map[int]int)
+	var playerFactions = make(map[int]string)
+	var lists = make(map[int]map[string]int)
+	if opts.Live {
+		// The database may hold the matches of a previous run, whose rows
+		// are reused rather than inserted again. The players can only be
+		// recognized if their identities are kept from run to run.
+		var known = opts.Identities != ""
+		err := readKnown(db, known, teams, players, playerFactions, lists)
+		if err != nil {
+			run.Fatal(status.Setup, "reading database", logger.M{
+				"path": globals.Database,
+				"err":  err,
+			})
+		}
+		if !known && len(teams) != 0 {
+			log.Warn("the players of a previous run won't be recognized without identities", nil)
+		}
+	}
+
+	for match := range matches {
+		// Matches are inserted whole, stop in between.
+		if ctx.Err() != nil {
+			break
+		}
+
+		if opts.Live {
+			// The match may have been inserted by a previous poll of the
+			// crawler or a previous run, whatever its status, remove it
+			// before inserting it again.
+			deleted, err := deleteMatch(db, match.Round, match.Zone)
+			if err != nil {
+				run.Error("deleting match", logger.M{
+					"round": match.Round,
+					"zone":  match.Zone,
+					"err":   err,
+				})
+				continue
+			}
+			if deleted {
+				log.Info("replacing match", logger.M{
+					"round":  match.Round,
+					"zone":   match.Zone,
+					"status": match.Status,
+				})
+			}
+		}
+
+		log.Debug("inserting match", logger.M{
+			"round": match.Round,
+			"zone":  match.Zone,
+		})
+		res, err := insert(db, "match", "insert into match (round, zone) values (?, ?)", match.Round, match.Zone)
+		if err != nil {
+			run.Error("inserting match", logger.M{
+				"err": err,
+			})
+			continue
+		}
+
+		matchID, _ := res.LastInsertId()
+		tracker.Add("loaded", 1)
+
+		var teamIDs [2]int
+		for i := 0; i <= 1; i++ {
+			var team = match.Teams[i]
+			var c, name, known = country.ParseTeam(team, match.Countries[i])
+			var key = teamKey(c.Code, name)
+			if ID, found := teams[key]; found {
+				teamIDs[i] = ID
+				continue
+			}
+
+			if !known {
+				checkViolations.Inc("team_without_country")
+				run.Error("unknown team country", logger.M{
+					"team": team,
+				})
+			}
+
+			log.Debug("inserting team", logger.M{
+				"country": c.Name,
+				"name":    name,
+			})
+			res, err := insert(db, "team", "insert into team (country, country_code, name) values (?, ?, ?)", c.Name, c.Code, name)
+			if err != nil {
+				run.Error("inserting team", logger.M{
+					"country": c.Name,
+					"name":    name,
+					"err":     err,
+				})
+				continue
+			}
+
+			ID, _ := res.LastInsertId()
+			teams[key] = int(ID)
+			teamIDs[i] = int(ID)
+		}
+
+		for _, game := range match.Games {
+			log.Debug("inserting game", logger.M{
+				"match_id": matchID,
+			})
+			res, err := insert(db, "game", "insert into game (match_id) values (?)", matchID)
+			if err != nil {
+				run.Error("inserting game", logger.M{
+					"match_id": matchID,
+					"err":      err,
+				})
+				continue
+			}
+
+			gameID, _ := res.LastInsertId()
+			tracker.Add("games", 1)
+			for i := 0; i <= 1; i++ {
+				var player = game.Players[i]
+				var person = registry.Resolve(player, match.Teams[i])
+				var caster = game.Lists[i]
+				var faction, known = factions.Casters[caster]
+				if !known {
+					checkViolations.Inc("unknown_caster")
+					run.Error("unknown caster faction", logger.M{
+						"player": player,
+						"caster": caster,
+					})
+				}
+
+				if _, found := players[person.ID]; !found {
+					log.Debug("inserting player", logger.M{
+						"name":        player,
+						"faction":     faction,
+						"team_id":     teamIDs[i],
+						"identity_id": person.ID,
+					})
+					res, err := insert(db, "player", "insert into player (name, faction, team_id, identity_id) values (?, ?, ?, ?)", player, faction, teamIDs[i], person.ID)
+					if err != nil {
+						run.Error("inserting player", logger.M{
+							"name":        player,
+							"faction":     faction,
+							"team_id":     teamIDs[i],
+							"identity_id": person.ID,
+							"err":         err,
+						})
+						continue
+					}
+
+					ID, _ := res.LastInsertId()
+					players[person.ID] = int(ID)
+					playerFactions[person.ID] = faction
+					lists[person.ID] = map[string]int{}
+				}
+
+				if known && playerFactions[person.ID] == "" {
+					// The player was first seen with an unknown caster, use
+					// the first known faction instead.
+					log.Info("updating player faction", logger.M{
+						"name":    player,
+						"faction": faction,
+					})
+					_, err := db.Exec("update player set faction = ? where id = ?", faction, players[person.ID])
+					if err != nil {
+						run.Error("updating player faction", logger.M{
+							"name":    player,
+							"faction": faction,
+							"err":     err,
+						})
+					} else {
+						playerFactions[person.ID] = faction
+					}
+				}
+
+				if known && faction != playerFactions[person.ID] {
+					checkViolations.Inc("mismatched_player_faction")
+					run.Error("mismatched player faction", logger.M{
+						"player":         player,
+						"caster":         caster,
+						"faction":        faction,
+						"player_faction": playerFactions[person.ID],
+					})
+				}
+
+				if _, found := lists[person.ID][caster]; !found {
+					log.Debug("inserting list", logger.M{
+						"player":  player,
+						"caster":  caster,
+						"faction": faction,
+					})
+					res, err := insert(db, "list", "insert into list (caster, faction, player_id) values (?, ?, ?)", caster, faction, players[person.ID])
+					if err != nil {
+						run.Error("inserting list", logger.M{
+							"player":  player,
+							"caster":  caster,
+							"faction": faction,
+							"err":     err,
+						})
+						continue
+					}
+
+					ID, _ := res.LastInsertId()
+					lists[person.ID][caster] = int(ID)
+				}
+
+				log.Debug("inserting report", logger.M{
+					"game_id": gameID,
+					"list_id": lists[person.ID][caster],
+				})
+				_, err = insert(db, "report", "insert into report (game_id, list_id, won) values (?, ?, ?)", gameID, lists[person.ID][caster], game.Winner == i)
+				if err != nil {
+					run.Error("inserting report", logger.M{
+						"game_id": gameID,
+						"list_id": lists[person.ID][caster],
+						"err":     err,
+					})
+					continue
+				}
+			}
+		}
+	}
+
+	db.Close()
+	if ctx.Err() != nil && !opts.Live {
+		run.Fatal(status.Interrupted, "interrupted", nil)
+	}
+
+	if !opts.Live {
+		err := tmp.Commit()
+		if err != nil {
+			run.Fatal(status.Setup, "writing database", logger.M{
+				"path": globals.Database,
+				"err":  err,
+			})
+		}
+	}
+
+	if opts.Identities != "" {
+		err := registry.Save(opts.Identities)
+		if err != nil {
+			run.Fatal(status.Setup, "saving identities", logger.M{
+				"path": opts.Identities,
+				"err":  err,
+			})
+		}
+	}
+}
+
+// teamKey returns the key of a team in the database.
+func teamKey(code, name string) string {
+	return code + " " + name
+}
+
+// readKnown fills the maps with the rows of the database: the teams, and the
+// players with their lists if requested.
+func readKnown(db *sqlx.DB, withPlayers bool, teams map[string]int, players map[int]int, playerFactions map[int]string, lists map[int]map[string]int) error {
+	var teamRows []struct {
+		ID   int    `db:"id"`
+		Code string `db:"country_code"`
+		Name string `db:"name"`
+	}
+	err := db.Select(&teamRows, "select id, coalesce(country_code, '') as country_code, coalesce(name, '') as name from team order by id")
+	if err != nil {
+		return err
+	}
+	for _, t := range teamRows {
+		teams[teamKey(t.Code, t.Name)] = t.ID
+	}
+
+	if !withPlayers {
+		return nil
+	}
+
+	var playerRows []struct {
+		ID         int    `db:"id"`
+		IdentityID int    `db:"identity_id"`
+		Faction    string `db:"faction"`
+	}
+	err = db.Select(&playerRows, "select id, identity_id, coalesce(faction, '') as faction from player order by id")
+	if err != nil {
+		return err
+	}
+	for _, p := range playerRows {
+		players[p.IdentityID] = p.ID
+		playerFactions[p.IdentityID] = p.Faction
+		lists[p.IdentityID] = map[string]int{}
+	}
+
+	var listRows []struct {
+		ID         int    `db:"id"`
+		Caster     string `db:"caster"`
+		IdentityID int    `db:"identity_id"`
+	}
+	err = db.Select(&listRows, "select list.id, list.caster, player.identity_id from list join player on player.id = list.player_id where player.id in (select max(id) from player group by identity_id) order by list.id")
+	if err != nil {
+		return err
+	}
+	for _, l := range listRows {
+		lists[l.IdentityID][l.Caster] = l.ID
+	}
+
+	return nil
+}
+
+// deleteMatch removes a match from the database, along with its games and
+// reports, and tells if there was one.
+func deleteMatch(db *sqlx.DB, round int, zone string) (bool, error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return false, err
+	}
+
+	var res sql.Result
+	for _, query := range []string{
+		"delete from report where game_id in (select game.id from game join match on match.id = game.match_id where round = ? and zone = ?)",
+		"delete from game where match_id in (select id from match where round = ? and zone = ?)",
+		"delete from match where round = ? and zone = ?",
+	} {
+		res, err = tx.Exec(query, round, zone)
+		if err != nil {
+			tx.Rollback()
+			return false, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n != 0, nil
+}
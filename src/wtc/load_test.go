@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// countRows returns the number of rows of each table of the database.
+func countRows(t *testing.T, path string) map[string]int {
+	db, err := sqlx.Connect("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var counts = make(map[string]int)
+	for _, table := range []string{"team", "player", "list", "match", "game", "report"} {
+		var n int
+		err := db.Get(&n, "select count(*) from "+table)
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts[table] = n
+	}
+	return counts
+}
+
+func TestLoadLiveTwice(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wtc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	globals.Database = filepath.Join(dir, "test.sqlite")
+	var opts = loadOptions{
+		Identities: filepath.Join(dir, "identities.json"),
+		Live:       true,
+	}
+
+	var input []Match
+	for zone := 1; zone <= 2; zone++ {
+		var match = Match{
+			Round: 1,
+			Zone:  fmt.Sprint(zone),
+			Teams: [2]string{"France Alpha", "Germany Beta"},
+		}
+		for g := range match.Games {
+			match.Games[g] = Game{
+				Players: [2]string{fmt.Sprintf("P%d%da", zone, g), fmt.Sprintf("P%d%db", zone, g)},
+				Lists:   [2]string{"Haley 1", "Vayl 1"},
+				Winner:  g % 2,
+			}
+		}
+		input = append(input, match)
+	}
+
+	// A restart of the live load reads the same matches again, without
+	// their status.
+	var counts []map[string]int
+	for i := 0; i < 2; i++ {
+		var matches = make(chan Match)
+		go func() {
+			defer close(matches)
+			for _, match := range input {
+				matches <- match
+			}
+		}()
+		loadMatches(context.Background(), matches, opts)
+		counts = append(counts, countRows(t, globals.Database))
+	}
+
+	var expected = map[string]int{
+		"team":   2,
+		"player": 20,
+		"list":   20,
+		"match":  2,
+		"game":   10,
+		"report": 20,
+	}
+	for i, c := range counts {
+		for table, n := range expected {
+			if c[table] != n {
+				t.Errorf("load %d: expected %d rows in %s, got %d", i+1, n, table, c[table])
+			}
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"interrupt"
+	"logger"
+	"os"
+	"progress"
+	"status"
+	"time"
+)
+
+// A Command is a subcommand of wtc. Its flags come in addition to the global
+// flags, which can be given before or after the name of the command.
+type Command struct {
+	Name    string
+	Summary string
+	Flags   *flag.FlagSet
+	Run     func(ctx context.Context)
+}
+
+// Globals are the flags shared by every command.
+type Globals struct {
+	Input      string
+	Quarantine string
+	Output     string
+	Database   string
+	Strict     bool
+	Progress   bool
+	Summary    string
+	Metrics    string
+	Logging    *logger.Flags
+}
+
+var (
+	log = logger.New(os.Stderr).With(logger.M{
+		"app": "wtc",
+	})
+	run      *status.Run
+	tracker  = progress.New()
+	globals  Globals
+	commands = []*Command{
+		crawlCommand,
+		fixCommand,
+		loadCommand,
+		checkCommand,
+		queryCommand,
+		statsCommand,
+		pipelineCommand,
+		exportCommand,
+		indexCommand,
+		diffCommand,
+		siteCommand,
+		serveCommand,
+	}
+)
+
+func (g *Globals) register(fs *flag.FlagSet) {
+	fs.StringVar(&g.Input, "in", "-", "input file")
+	fs.StringVar(&g.Quarantine, "quarantine", "", "file to copy the malformed lines of the input into")
+	fs.StringVar(&g.Output, "out", "-", "output file")
+	fs.StringVar(&g.Database, "db", "data.sqlite", "database file")
+	fs.BoolVar(&g.Strict, "strict", false, "stop on the first error")
+	fs.BoolVar(&g.Progress, "progress", true, "show the progress of the run when on a terminal")
+	fs.StringVar(&g.Summary, "summary", "", "file to write the JSON summary of the run into")
+	fs.StringVar(&g.Metrics, "metrics", "", "address to serve the Prometheus metrics of the run on, at /metrics")
+	g.Logging = logger.RegisterFlags(fs)
+}
+
+func main() {
+	globals.register(flag.CommandLine)
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(status.Usage)
+	}
+
+	var name = flag.Arg(0)
+	if name == "help" {
+		help(flag.Arg(1))
+	}
+
+	var cmd = find(name)
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "wtc: unknown command %q\n", name)
+		usage()
+		os.Exit(status.Usage)
+	}
+
+	// The command is parsed with both its own flags and the global ones.
+	var fs = flag.NewFlagSet(cmd.Name, flag.ExitOnError)
+	for _, set := range []*flag.FlagSet{cmd.Flags, flag.CommandLine} {
+		set.VisitAll(func(f *flag.Flag) {
+			fs.Var(f.Value, f.Name, f.Usage)
+		})
+	}
+	fs.Usage = func() {
+		commandUsage(cmd)
+	}
+	fs.Parse(flag.Args()[1:])
+
+	if fs.NArg() != 0 {
+		fmt.Fprintf(os.Stderr, "wtc %s: unexpected arguments %q\n", cmd.Name, fs.Args())
+		commandUsage(cmd)
+		os.Exit(status.Usage)
+	}
+
+	log = log.With(logger.M{
+		"cmd": cmd.Name,
+	})
+
+	run = status.New(log)
+
+	closeLog, err := globals.Logging.Apply(log)
+	if err != nil {
+		run.Fatal(status.Usage, "configuring logger", logger.M{
+			"err": err,
+		})
+	}
+
+	// The log file is closed last, once everything is logged.
+	run.OnExit(func(int) {
+		closeLog()
+	})
+
+	run.Strict = globals.Strict
+
+	if globals.Metrics != "" {
+		err := registry.Listen(globals.Metrics)
+		if err != nil {
+			run.Fatal(status.Setup, "serving metrics", logger.M{
+				"addr": globals.Metrics,
+				"err":  err,
+			})
+		}
+		log.Info("serving metrics", logger.M{
+			"addr": globals.Metrics,
+		})
+	}
+
+	if globals.Summary != "" {
+		run.OnExit(func(code int) {
+			writeSummary(cmd, code)
+		})
+	}
+
+	if globals.Progress && !globals.Logging.Silent && progress.IsTerminal(os.Stderr) {
+		var bar = progress.NewBar(tracker, os.Stderr)
+		bar.Extra = func() string {
+			if n := run.Errors(); n != 0 {
+				return fmt.Sprintf("errors %d", n)
+			}
+			return ""
+		}
+		log.SetOutput(bar)
+		bar.Start(200 * time.Millisecond)
+		run.OnExit(func(int) {
+			bar.Stop()
+		})
+	}
+
+	cmd.Run(interrupt.Context())
+	run.Exit()
+}
+
+// find returns the command of the given name, if any.
+func find(name string) *Command {
+	for _, cmd := range commands {
+		if cmd.Name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// help prints the usage of the given command, or the general usage if none,
+// and exits.
+func help(name string) {
+	if name == "" {
+		usage()
+		os.Exit(status.OK)
+	}
+
+	var cmd = find(name)
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "wtc: unknown command %q\n", name)
+		usage()
+		os.Exit(status.Usage)
+	}
+
+	commandUsage(cmd)
+	os.Exit(status.OK)
+}
+
+func usage() {
+	var out = flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: wtc [flags] <command> [flags]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(out, "  %-10s %s\n", cmd.Name, cmd.Summary)
+	}
+	fmt.Fprintf(out, "\nGlobal flags:\n")
+	flag.CommandLine.PrintDefaults()
+	fmt.Fprintf(out, "\nRun \"wtc help <command>\" for the flags of a command.\n")
+}
+
+func commandUsage(cmd *Command) {
+	var out = flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: wtc %s [flags]\n\n%s.\n", cmd.Name, cmd.Summary)
+
+	var hasFlags bool
+	cmd.Flags.VisitAll(func(*flag.Flag) {
+		hasFlags = true
+	})
+	if hasFlags {
+		fmt.Fprintf(out, "\nFlags:\n")
+		cmd.Flags.SetOutput(out)
+		cmd.Flags.PrintDefaults()
+	}
+
+	fmt.Fprintf(out, "\nGlobal flags:\n")
+	flag.CommandLine.PrintDefaults()
+}
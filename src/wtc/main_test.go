@@ -0,0 +1,16 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"logger"
+	"status"
+)
+
+func TestMain(m *testing.M) {
+	log = logger.New(ioutil.Discard)
+	run = status.New(log)
+	os.Exit(m.Run())
+}
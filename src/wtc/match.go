@@ -0,0 +1,193 @@
+package main
+
+import (
+	"atomicfile"
+	"context"
+	"encoding/json"
+	"io"
+	"jsonl"
+	"logger"
+	"os"
+	"status"
+)
+
+type (
+	// A Match is a match of the crawler files, as read by every command.
+	Match struct {
+		Round     int
+		Zone      string
+		Teams     [2]string
+		Countries [2]string
+		Games     [5]Game
+		Status    string `json:",omitempty"`
+	}
+
+	Game struct {
+		Players [2]string
+		Lists   [2]string
+		Winner  int
+	}
+)
+
+// Statuses of the matches emitted by the watch mode.
+const (
+	StatusNew     = "new"
+	StatusUpdated = "updated"
+)
+
+// zoneLess orders the zones numerically when possible.
+func zoneLess(a, b string) bool {
+	if len(a) != len(b) {
+		return len(a) < len(b)
+	}
+	return a < b
+}
+
+// readMatches reads the matches of the input file, one per line. Malformed
+// lines are reported and skipped.
+func readMatches(ctx context.Context) <-chan Match {
+	var in io.Reader = os.Stdin
+	if globals.Input != "-" {
+		file, err := os.Open(globals.Input)
+		if err != nil {
+			run.Fatal(status.Setup, "opening input file", logger.M{
+				"path": globals.Input,
+				"err":  err,
+			})
+		}
+		in = file
+	}
+
+	var reader = jsonl.NewReader(in)
+	if globals.Quarantine != "" {
+		file, err := os.Create(globals.Quarantine)
+		if err != nil {
+			run.Fatal(status.Setup, "creating quarantine file", logger.M{
+				"path": globals.Quarantine,
+				"err":  err,
+			})
+		}
+		reader.Quarantine = file
+	}
+
+	var matches = make(chan Match)
+	go func() {
+		defer close(matches)
+		for {
+			var match Match
+			err := reader.Read(&match)
+			if err == io.EOF {
+				return
+			}
+			if malformed, ok := err.(*jsonl.Error); ok {
+				parseFailures.Inc("input")
+				run.Error("reading match", logger.M{
+					"line":   malformed.Line,
+					"offset": malformed.Offset,
+					"err":    malformed.Err,
+				})
+				continue
+			}
+			if err != nil {
+				run.Fatal(status.Setup, "reading input file", logger.M{
+					"path": globals.Input,
+					"err":  err,
+				})
+			}
+
+			select {
+			case matches <- match:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return matches
+}
+
+// createOutput returns the output of the matches, and a function moving it to
+// its destination once complete. The output is removed if the run fails.
+func createOutput() (io.Writer, func()) {
+	if globals.Output == "-" {
+		return os.Stdout, func() {}
+	}
+
+	file, err := atomicfile.Create(globals.Output)
+	if err != nil {
+		run.Fatal(status.Setup, "creating output file", logger.M{
+			"path": globals.Output,
+			"err":  err,
+		})
+	}
+	run.OnFailure(func() {
+		file.Abort()
+	})
+
+	return file, func() {
+		err := file.Commit()
+		if err != nil {
+			run.Fatal(status.Setup, "writing output file", logger.M{
+				"path": globals.Output,
+				"err":  err,
+			})
+		}
+	}
+}
+
+// streamOutput returns the output of the matches of a run which doesn't end,
+// such as the watch mode, and a function closing it. The matches are
+// appended to the output file and synced as they are written, so it can be
+// followed and nothing is lost if the run is killed.
+func streamOutput() (io.Writer, func()) {
+	if globals.Output == "-" {
+		return os.Stdout, func() {}
+	}
+
+	file, err := os.OpenFile(globals.Output, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		run.Fatal(status.Setup, "opening output file", logger.M{
+			"path": globals.Output,
+			"err":  err,
+		})
+	}
+
+	return syncWriter{file}, func() {
+		err := file.Close()
+		if err != nil {
+			run.Fatal(status.Setup, "writing output file", logger.M{
+				"path": globals.Output,
+				"err":  err,
+			})
+		}
+	}
+}
+
+// A syncWriter syncs its file after each write.
+type syncWriter struct {
+	*os.File
+}
+
+func (w syncWriter) Write(p []byte) (int, error) {
+	n, err := w.File.Write(p)
+	if err != nil {
+		return n, err
+	}
+	return n, w.File.Sync()
+}
+
+// writeMatch writes a match on the output.
+func writeMatch(encoder *json.Encoder, match Match) {
+	log.Debug("writing match", logger.M{
+		"round":  match.Round,
+		"zone":   match.Zone,
+		"status": match.Status,
+	})
+	err := encoder.Encode(match)
+	if err != nil {
+		run.Error("writing match", logger.M{
+			"match": match,
+			"err":   err,
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"database/sql"
+	"metrics"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// The metrics of the runs, served on /metrics when the -metrics flag is set.
+var (
+	registry = metrics.NewRegistry()
+
+	fetchRequests = registry.Counter(
+		"wtc_fetch_requests_total",
+		"Number of HTTP requests made to the WTC website, by response status (0 if there was no response).",
+		"status",
+	)
+	fetchDuration = registry.Histogram(
+		"wtc_fetch_duration_seconds",
+		"Duration of the HTTP requests made to the WTC website.",
+		metrics.DefaultBuckets,
+	)
+	parseFailures = registry.Counter(
+		"wtc_parse_failures_total",
+		"Number of pages or input lines which couldn't be parsed, by source (page or input).",
+		"source",
+	)
+	matchesEmitted = registry.Counter(
+		"wtc_matches_emitted_total",
+		"Number of matches emitted by the crawler.",
+	)
+	insertDuration = registry.Histogram(
+		"wtc_db_insert_duration_seconds",
+		"Duration of the insertions into the database, by table.",
+		metrics.DefaultBuckets,
+		"table",
+	)
+	checkViolations = registry.Counter(
+		"wtc_check_violations_total",
+		"Number of suspicious data found in the matches or in the database, by check.",
+		"check",
+	)
+)
+
+// observeFetch records a request made to the WTC website.
+func observeFetch(status int, elapsed time.Duration) {
+	fetchRequests.Inc(strconv.Itoa(status))
+	fetchDuration.Observe(elapsed.Seconds())
+}
+
+// insert runs an insertion into the given table, timing it.
+func insert(db *sqlx.DB, table, query string, args ...interface{}) (sql.Result, error) {
+	var start = time.Now()
+	res, err := db.Exec(query, args...)
+	insertDuration.Observe(time.Since(start).Seconds(), table)
+	return res, err
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"flag"
+)
+
+var (
+	pipelineCommand = &Command{
+		Name:    "pipeline",
+		Summary: "Crawl, fix and load the matches into the database in one go",
+		Flags:   flag.NewFlagSet("pipeline", flag.ExitOnError),
+		Run:     runPipeline,
+	}
+	pipelineCrawlOpts crawlOptions
+	pipelineLoadOpts  loadOptions
+)
+
+func init() {
+	pipelineCrawlOpts.register(pipelineCommand.Flags)
+	pipelineLoadOpts.register(pipelineCommand.Flags)
+}
+
+func runPipeline(ctx context.Context) {
+	// A watched event never ends, so its matches can't wait for the end of
+	// the crawl to be visible in the database.
+	if pipelineCrawlOpts.Watch {
+		pipelineLoadOpts.Live = true
+	}
+
+	loadMatches(ctx, fixMatches(ctx, crawlMatches(ctx, pipelineCrawlOpts)), pipelineLoadOpts)
+}
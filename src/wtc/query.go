@@ -0,0 +1,371 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"logger"
+	"os"
+	"path/filepath"
+	"sort"
+	"status"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type (
+	// A Report is a named SQL query of the database. The leading comment
+	// lines of its file are its description, except for a "version: <n>"
+	// line giving its version.
+	Report struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		Version     int    `json:"version"`
+		Query       string `json:"-"`
+	}
+
+	// A Result is the output of a report.
+	Result struct {
+		Report  string                   `json:"report"`
+		Version int                      `json:"version,omitempty"`
+		Params  map[string]interface{}   `json:"params"`
+		Columns []string                 `json:"columns"`
+		Rows    []map[string]interface{} `json:"rows"`
+	}
+
+	queryOptions struct {
+		Report  string
+		Reports string
+		Format  string
+		Round   int
+		Faction string
+		Params  params
+	}
+
+	// params are the additional parameters of the reports, given as
+	// name=value.
+	params map[string]string
+)
+
+var (
+	queryCommand = &Command{
+		Name:    "query",
+		Summary: "Run a named report on the database, or list the reports",
+		Flags:   flag.NewFlagSet("query", flag.ExitOnError),
+		Run:     runQuery,
+	}
+	queryOpts queryOptions
+
+	// formats are the writers of the results, by format.
+	formats = map[string]func(io.Writer, Result) error{
+		"table": writeTable,
+		"csv":   writeCSV,
+		"json":  writeJSON,
+	}
+)
+
+func init() {
+	queryOpts.register(queryCommand.Flags)
+}
+
+func (o *queryOptions) register(fs *flag.FlagSet) {
+	o.Params = make(params)
+	fs.StringVar(&o.Report, "report", "", "name of the report to run, the reports are listed if empty")
+	fs.StringVar(&o.Reports, "reports", "", "directory of .sql reports adding to or overriding the default ones")
+	fs.StringVar(&o.Format, "format", "table", "output format (table, csv or json)")
+	fs.IntVar(&o.Round, "round", 0, "value of the :round parameter, 0 for every round")
+	fs.StringVar(&o.Faction, "faction", "", "value of the :faction parameter, empty for every faction")
+	fs.Var(o.Params, "param", "additional parameter of the report, as name=value (repeatable)")
+}
+
+func runQuery(ctx context.Context) {
+	reports, err := loadReports(queryOpts.Reports)
+	if err != nil {
+		run.Fatal(status.Setup, "loading reports", logger.M{
+			"path": queryOpts.Reports,
+			"err":  err,
+		})
+	}
+
+	if queryOpts.Report == "" {
+		listReports(reports)
+		return
+	}
+
+	report, found := reports[queryOpts.Report]
+	if !found {
+		run.Fatal(status.Usage, "unknown report", logger.M{
+			"report": queryOpts.Report,
+		})
+	}
+
+	write, found := formats[queryOpts.Format]
+	if !found {
+		run.Fatal(status.Usage, "unknown format", logger.M{
+			"format": queryOpts.Format,
+		})
+	}
+
+	db, err := sqlx.Connect("sqlite3", "file:"+globals.Database+"?mode=ro")
+	if err != nil {
+		run.Fatal(status.Setup, "opening database", logger.M{
+			"path": globals.Database,
+			"err":  err,
+		})
+	}
+	defer db.Close()
+
+	var args = map[string]interface{}{
+		"round":   queryOpts.Round,
+		"faction": queryOpts.Faction,
+	}
+	for name, value := range queryOpts.Params {
+		args[name] = value
+	}
+
+	log.Info("running report", logger.M{
+		"report":  report.Name,
+		"version": report.Version,
+		"params":  args,
+	})
+	result, err := query(ctx, db, report, args)
+	if err != nil {
+		run.Fatal(status.Setup, "running report", logger.M{
+			"report": report.Name,
+			"err":    err,
+		})
+	}
+
+	out, commit := createOutput()
+	err = write(out, result)
+	if err != nil {
+		run.Fatal(status.Setup, "writing result", logger.M{
+			"path": globals.Output,
+			"err":  err,
+		})
+	}
+	commit()
+}
+
+// loadReports returns the default reports, along with the .sql files of the
+// given directory, if any, which override the reports of the same name.
+func loadReports(dir string) (map[string]Report, error) {
+	var reports = make(map[string]Report)
+	sub, err := fs.Sub(Reports, "reports")
+	if err != nil {
+		return nil, err
+	}
+	err = readReports(reports, sub, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if dir == "" {
+		return reports, nil
+	}
+	err = readReports(reports, os.DirFS(dir), dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return reports, nil
+}
+
+// readReports adds the .sql files of fsys to the reports, overriding the
+// ones of the same name. dir is where fsys was read from, for the messages.
+func readReports(reports map[string]Report, fsys fs.FS, dir string) error {
+	paths, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		text, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return err
+		}
+
+		var name = strings.TrimSuffix(path, ".sql")
+		path = filepath.Join(dir, path)
+		report, err := parseReport(name, string(text))
+		if err != nil {
+			return fmt.Errorf("%s: %s", path, err)
+		}
+
+		if previous, found := reports[name]; found {
+			log.Info("overriding report", logger.M{
+				"report":  name,
+				"path":    path,
+				"version": report.Version,
+				"default": previous.Version,
+			})
+		}
+		reports[name] = report
+	}
+
+	return nil
+}
+
+// parseReport reads a report from its query.
+func parseReport(name, text string) (Report, error) {
+	var report = Report{
+		Name:    name,
+		Version: 1,
+	}
+
+	// The header is removed from the query, as its colons would be taken for
+	// parameters.
+	var description []string
+	var lines = strings.Split(text, "\n")
+	for len(lines) != 0 {
+		var line = strings.TrimSpace(lines[0])
+		if !strings.HasPrefix(line, "--") {
+			break
+		}
+		lines = lines[1:]
+
+		line = strings.TrimSpace(strings.TrimPrefix(line, "--"))
+		if version := strings.TrimPrefix(line, "version:"); version != line {
+			n, err := strconv.Atoi(strings.TrimSpace(version))
+			if err != nil {
+				return report, fmt.Errorf("invalid version %q", strings.TrimSpace(version))
+			}
+			report.Version = n
+			continue
+		}
+		if line != "" {
+			description = append(description, line)
+		}
+	}
+	report.Description = strings.Join(description, " ")
+	report.Query = strings.Join(lines, "\n")
+
+	return report, nil
+}
+
+// listReports prints the name, version and description of the reports.
+func listReports(reports map[string]Report) {
+	var names = make([]string, 0, len(reports))
+	for name := range reports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var w = tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	for _, name := range names {
+		var report = reports[name]
+		fmt.Fprintf(w, "%s\tv%d\t%s\n", report.Name, report.Version, report.Description)
+	}
+	w.Flush()
+}
+
+// query runs the report with the given parameters, referenced as :name in
+// its query.
+func query(ctx context.Context, db *sqlx.DB, report Report, args map[string]interface{}) (Result, error) {
+	var result = Result{
+		Report:  report.Name,
+		Version: report.Version,
+		Params:  args,
+		Rows:    []map[string]interface{}{},
+	}
+
+	q, values, err := sqlx.Named(report.Query, args)
+	if err != nil {
+		return result, err
+	}
+
+	rows, err := db.QueryxContext(ctx, db.Rebind(q), values...)
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+
+	result.Columns, err = rows.Columns()
+	if err != nil {
+		return result, err
+	}
+
+	for rows.Next() {
+		values, err := rows.SliceScan()
+		if err != nil {
+			return result, err
+		}
+
+		var row = make(map[string]interface{}, len(values))
+		for i, v := range values {
+			if b, ok := v.([]byte); ok {
+				v = string(b)
+			}
+			row[result.Columns[i]] = v
+		}
+		result.Rows = append(result.Rows, row)
+	}
+
+	return result, rows.Err()
+}
+
+// format returns the text of a value of a result, NULL being empty.
+func format(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+func writeTable(out io.Writer, result Result) error {
+	var w = tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(result.Columns, "\t"))
+	for _, row := range result.Rows {
+		var cells = make([]string, len(result.Columns))
+		for i, col := range result.Columns {
+			cells[i] = format(row[col])
+		}
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
+	}
+	return w.Flush()
+}
+
+func writeCSV(out io.Writer, result Result) error {
+	var w = csv.NewWriter(out)
+	_ = w.Write(result.Columns)
+	for _, row := range result.Rows {
+		var record = make([]string, len(result.Columns))
+		for i, col := range result.Columns {
+			record[i] = format(row[col])
+		}
+		_ = w.Write(record)
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func writeJSON(out io.Writer, result Result) error {
+	var enc = json.NewEncoder(out)
+	enc.SetIndent("", "\t")
+	return enc.Encode(result)
+}
+
+func (p params) String() string {
+	var pairs []string
+	for name, value := range p {
+		pairs = append(pairs, name+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (p params) Set(s string) error {
+	var parts = strings.SplitN(s, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return fmt.Errorf("expected name=value, got %q", s)
+	}
+	p[parts[0]] = parts[1]
+	return nil
+}
@@ -0,0 +1,11 @@
+package main
+
+import "embed"
+
+// Reports are the default reports of the query command, one .sql file each,
+// read like the ones of the -reports directory. Their leading comment lines
+// are their description and version, and they take the :round and :faction
+// parameters, 0 and the empty string meaning every round and faction.
+//
+//go:embed reports/*.sql
+var Reports embed.FS
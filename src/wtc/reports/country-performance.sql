@@ -0,0 +1,19 @@
+-- Games, wins and win rate of the teams of each country.
+-- version: 1
+select
+	team.country as country,
+	count(distinct team.id) as teams,
+	count(*) as games,
+	sum(report.won) as wins,
+	count(*) - sum(report.won) as losses,
+	round(100.0 * sum(report.won) / count(*), 1) as win_rate
+from report
+join list on list.id = report.list_id
+join player on player.id = list.player_id
+join team on team.id = player.team_id
+join game on game.id = report.game_id
+join match on match.id = game.match_id
+where (:round = 0 or match.round = :round)
+	and (:faction = '' or list.faction = :faction)
+group by team.country
+order by win_rate desc, games desc, country
@@ -0,0 +1,16 @@
+-- Games, wins and win rate of each faction.
+-- version: 1
+select
+	list.faction as faction,
+	count(*) as games,
+	sum(report.won) as wins,
+	count(*) - sum(report.won) as losses,
+	round(100.0 * sum(report.won) / count(*), 1) as win_rate
+from report
+join list on list.id = report.list_id
+join game on game.id = report.game_id
+join match on match.id = game.match_id
+where (:round = 0 or match.round = :round)
+	and (:faction = '' or list.faction = :faction)
+group by list.faction
+order by win_rate desc, games desc, faction
@@ -0,0 +1,18 @@
+-- Casters by number of games played, with the number of players who
+-- brought them and their win rate.
+-- version: 1
+select
+	list.caster as caster,
+	list.faction as faction,
+	count(*) as games,
+	count(distinct list.player_id) as players,
+	sum(report.won) as wins,
+	round(100.0 * sum(report.won) / count(*), 1) as win_rate
+from report
+join list on list.id = report.list_id
+join game on game.id = report.game_id
+join match on match.id = game.match_id
+where (:round = 0 or match.round = :round)
+	and (:faction = '' or list.faction = :faction)
+group by list.caster, list.faction
+order by games desc, caster
@@ -0,0 +1,20 @@
+-- The twenty players with the most wins, then the best win rate.
+-- version: 1
+select
+	player.name as player,
+	team.name as team,
+	player.faction as faction,
+	count(*) as games,
+	sum(report.won) as wins,
+	round(100.0 * sum(report.won) / count(*), 1) as win_rate
+from report
+join list on list.id = report.list_id
+join player on player.id = list.player_id
+join team on team.id = player.team_id
+join game on game.id = report.game_id
+join match on match.id = game.match_id
+where (:round = 0 or match.round = :round)
+	and (:faction = '' or list.faction = :faction)
+group by player.id
+order by wins desc, win_rate desc, player.name
+limit 20
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"logger"
+	"net/http"
+	"server"
+	"status"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type serveOptions struct {
+	Address string
+}
+
+var (
+	serveCommand = &Command{
+		Name:    "serve",
+		Summary: "Serve the tables of the database as a read-only JSON API",
+		Flags:   flag.NewFlagSet("serve", flag.ExitOnError),
+		Run:     runServe,
+	}
+	serveOpts serveOptions
+)
+
+func init() {
+	serveOpts.register(serveCommand.Flags)
+}
+
+func (o *serveOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.Address, "addr", ":8080", "listening address")
+}
+
+func runServe(ctx context.Context) {
+	db, err := sqlx.Connect("sqlite3", "file:"+globals.Database+"?mode=ro")
+	if err != nil {
+		run.Fatal(status.Setup, "opening database", logger.M{
+			"path": globals.Database,
+			"err":  err,
+		})
+	}
+	defer db.Close()
+
+	var srv = &http.Server{
+		Addr:    serveOpts.Address,
+		Handler: server.New(db, log, run, registry),
+	}
+
+	// The server is stopped on interruption, once the requests being served
+	// are answered.
+	var stopped = make(chan struct{})
+	go func() {
+		<-ctx.Done()
+		_ = srv.Shutdown(context.Background())
+		close(stopped)
+	}()
+
+	log.Info("listening", logger.M{
+		"addr": serveOpts.Address,
+	})
+	err = srv.ListenAndServe()
+	if err != http.ErrServerClosed {
+		run.Fatal(status.Setup, "listening", logger.M{
+			"addr": serveOpts.Address,
+			"err":  err,
+		})
+	}
+
+	<-stopped
+	log.Info("interrupted", nil)
+}
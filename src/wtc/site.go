@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"logger"
+	"path/filepath"
+	"site"
+	"status"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// SiteDirectory is the output directory of the site when -out isn't set.
+const SiteDirectory = "site"
+
+type siteOptions struct {
+	Templates string
+}
+
+var (
+	siteCommand = &Command{
+		Name:    "site",
+		Summary: "Build a static website of the results of the database in the output directory",
+		Flags:   flag.NewFlagSet("site", flag.ExitOnError),
+		Run:     runSite,
+	}
+	siteOpts siteOptions
+)
+
+func init() {
+	siteOpts.register(siteCommand.Flags)
+}
+
+func (o *siteOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.Templates, "templates", "", "directory of templates overriding the default ones")
+}
+
+func runSite(ctx context.Context) {
+	// The site is a directory, it can't be written on the standard output.
+	var dir = globals.Output
+	if dir == "-" {
+		dir = SiteDirectory
+	}
+
+	db, err := sqlx.Connect("sqlite3", "file:"+globals.Database+"?mode=ro")
+	if err != nil {
+		run.Fatal(status.Setup, "opening database", logger.M{
+			"path": globals.Database,
+			"err":  err,
+		})
+	}
+	defer db.Close()
+
+	s, err := site.Load(db, run)
+	if err != nil {
+		run.Fatal(status.Setup, "loading database", logger.M{
+			"path": globals.Database,
+			"err":  err,
+		})
+	}
+
+	tpl, err := site.ParseTemplates(siteOpts.Templates)
+	if err != nil {
+		run.Fatal(status.Setup, "parsing templates", logger.M{
+			"path": siteOpts.Templates,
+			"err":  err,
+		})
+	}
+
+	for _, page := range s.Pages() {
+		if ctx.Err() != nil {
+			run.Fatal(status.Interrupted, "interrupted", nil)
+		}
+
+		var path = filepath.Join(dir, page.Path)
+		log.Info("rendering page", logger.M{
+			"path": path,
+		})
+		err = s.Render(tpl, dir, page)
+		if err != nil {
+			run.Error("rendering page", logger.M{
+				"path": path,
+				"err":  err,
+			})
+		}
+	}
+}
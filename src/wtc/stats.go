@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"logger"
+	"math"
+	"sort"
+	"stats"
+	"status"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type (
+	// A Side is a player of a game along with their opponent.
+	Side struct {
+		Game            int    `db:"game"`
+		Player          int    `db:"player"`
+		Faction         string `db:"faction"`
+		Caster          string `db:"caster"`
+		Opponent        int    `db:"opponent"`
+		OpponentFaction string `db:"opponent_faction"`
+		OpponentCaster  string `db:"opponent_caster"`
+		Round           int    `db:"round"`
+		Won             bool   `db:"won"`
+	}
+
+	// A Record counts the games of a group: a faction, a caster or a
+	// matchup.
+	Record struct {
+		Name     string
+		Games    int
+		Wins     int
+		Expected float64
+	}
+
+	statsOptions struct {
+		By      string
+		Format  string
+		Round   int
+		Faction string
+		Level   float64
+		Prior   float64
+		K       float64
+	}
+)
+
+var (
+	statsCommand = &Command{
+		Name:    "stats",
+		Summary: "Report the win rates of the factions, casters or matchups with their significance",
+		Flags:   flag.NewFlagSet("stats", flag.ExitOnError),
+		Run:     runStats,
+	}
+	statsOpts statsOptions
+
+	// groups are the keys of the sides of a game by grouping, empty if the
+	// side isn't counted.
+	groups = map[string]func(Side) string{
+		"faction": func(s Side) string {
+			if s.Faction == "" || s.Faction == s.OpponentFaction {
+				return ""
+			}
+			return s.Faction
+		},
+		"caster": func(s Side) string {
+			if s.Faction == "" || s.Caster == s.OpponentCaster {
+				return ""
+			}
+			return s.Caster
+		},
+		"matchup": func(s Side) string {
+			if s.Faction == "" || s.OpponentFaction == "" || s.Faction == s.OpponentFaction {
+				return ""
+			}
+			// Each game is counted once, from the side of the filtered
+			// faction if any, else of the first faction by name.
+			if statsOpts.Faction == "" && s.Faction > s.OpponentFaction {
+				return ""
+			}
+			return s.Faction + " vs " + s.OpponentFaction
+		},
+	}
+)
+
+func init() {
+	statsOpts.register(statsCommand.Flags)
+}
+
+func (o *statsOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.By, "by", "faction", "grouping of the games (faction, caster or matchup)")
+	fs.StringVar(&o.Format, "format", "table", "output format (table, csv or json)")
+	fs.IntVar(&o.Round, "round", 0, "only count the given round, 0 for every round")
+	fs.StringVar(&o.Faction, "faction", "", "only count the games of the given faction")
+	fs.Float64Var(&o.Level, "level", 0.95, "level of the intervals and significance tests")
+	fs.Float64Var(&o.Prior, "prior", 1, "parameter of the symmetric Beta prior of the credible intervals (1 for uniform, 0.5 for Jeffreys)")
+	fs.Float64Var(&o.K, "elo-k", 32, "maximum number of rating points exchanged in a game")
+}
+
+func runStats(ctx context.Context) {
+	group, found := groups[statsOpts.By]
+	if !found {
+		run.Fatal(status.Usage, "unknown grouping", logger.M{
+			"by": statsOpts.By,
+		})
+	}
+
+	write, found := formats[statsOpts.Format]
+	if !found {
+		run.Fatal(status.Usage, "unknown format", logger.M{
+			"format": statsOpts.Format,
+		})
+	}
+
+	if statsOpts.Level <= 0 || statsOpts.Level >= 1 {
+		run.Fatal(status.Usage, "invalid level", logger.M{
+			"level": statsOpts.Level,
+		})
+	}
+
+	if statsOpts.Prior <= 0 {
+		run.Fatal(status.Usage, "invalid prior", logger.M{
+			"prior": statsOpts.Prior,
+		})
+	}
+
+	db, err := sqlx.Connect("sqlite3", "file:"+globals.Database+"?mode=ro")
+	if err != nil {
+		run.Fatal(status.Setup, "opening database", logger.M{
+			"path": globals.Database,
+			"err":  err,
+		})
+	}
+	defer db.Close()
+
+	// Both sides of every game, the games in the order they were played.
+	var sides []Side
+	err = db.SelectContext(ctx, &sides, `
+		select
+			game.id as game,
+			list.player_id as player,
+			list.faction as faction,
+			list.caster as caster,
+			opponent_list.player_id as opponent,
+			opponent_list.faction as opponent_faction,
+			opponent_list.caster as opponent_caster,
+			match.round as round,
+			report.won as won
+		from report
+		join list on list.id = report.list_id
+		join game on game.id = report.game_id
+		join match on match.id = game.match_id
+		join report as opponent_report on opponent_report.game_id = report.game_id and opponent_report.id != report.id
+		join list as opponent_list on opponent_list.id = opponent_report.list_id
+		order by match.round, match.zone, game.id, report.id
+	`)
+	if err != nil {
+		run.Fatal(status.Setup, "reading games", logger.M{
+			"err": err,
+		})
+	}
+
+	// The players are rated along the history, and the expected result of
+	// each game comes from the ratings of its players before it, so it
+	// doesn't know of its outcome. The games are rated whatever the filters.
+	var elo = stats.NewElo(statsOpts.K)
+	var records = make(map[string]*Record)
+	for start := 0; start < len(sides); {
+		var end = start
+		for end < len(sides) && sides[end].Game == sides[start].Game {
+			end++
+		}
+		var game = sides[start:end]
+		start = end
+
+		for _, side := range game {
+			count(records, group, side, elo.Expected(side.Player, side.Opponent))
+		}
+		for _, side := range game {
+			if side.Won {
+				elo.Update(side.Player, side.Opponent)
+			}
+		}
+	}
+
+	var sorted = make([]*Record, 0, len(records))
+	for _, record := range records {
+		sorted = append(sorted, record)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Games != sorted[j].Games {
+			return sorted[i].Games > sorted[j].Games
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	var result = Result{
+		Report: statsOpts.By,
+		Params: map[string]interface{}{
+			"round":   statsOpts.Round,
+			"faction": statsOpts.Faction,
+			"level":   statsOpts.Level,
+			"prior":   statsOpts.Prior,
+			"elo_k":   statsOpts.K,
+		},
+		Columns: []string{
+			statsOpts.By,
+			"games",
+			"wins",
+			"win_rate",
+			"wilson_low",
+			"wilson_high",
+			"credible_low",
+			"credible_high",
+			"expected_win_rate",
+			"adjusted_win_rate",
+			"p_value",
+			"significant",
+		},
+		Rows: []map[string]interface{}{},
+	}
+	for _, record := range sorted {
+		var rate = float64(record.Wins) / float64(record.Games)
+		var expected = record.Expected / float64(record.Games)
+		var wilson = stats.Wilson(record.Wins, record.Games, statsOpts.Level)
+		var credible = stats.Credible(record.Wins, record.Games, statsOpts.Prior, statsOpts.Prior, statsOpts.Level)
+		var p = stats.BinomialTest(record.Wins, record.Games, 0.5)
+
+		result.Rows = append(result.Rows, map[string]interface{}{
+			statsOpts.By:        record.Name,
+			"games":             record.Games,
+			"wins":              record.Wins,
+			"win_rate":          round3(rate),
+			"wilson_low":        round3(wilson.Low),
+			"wilson_high":       round3(wilson.High),
+			"credible_low":      round3(credible.Low),
+			"credible_high":     round3(credible.High),
+			"expected_win_rate": round3(expected),
+			"adjusted_win_rate": round3(math.Max(0, math.Min(1, 0.5+rate-expected))),
+			"p_value":           round3(p),
+			"significant":       p < 1-statsOpts.Level,
+		})
+	}
+
+	out, commit := createOutput()
+	err = write(out, result)
+	if err != nil {
+		run.Fatal(status.Setup, "writing result", logger.M{
+			"path": globals.Output,
+			"err":  err,
+		})
+	}
+	commit()
+}
+
+// count adds a side of a game to the record of its group, if it is counted.
+func count(records map[string]*Record, group func(Side) string, side Side, expected float64) {
+	if statsOpts.Round != 0 && side.Round != statsOpts.Round {
+		return
+	}
+	if statsOpts.Faction != "" && side.Faction != statsOpts.Faction {
+		return
+	}
+
+	var name = group(side)
+	if name == "" {
+		return
+	}
+
+	var record, found = records[name]
+	if !found {
+		record = &Record{Name: name}
+		records[name] = record
+	}
+	record.Games++
+	if side.Won {
+		record.Wins++
+	}
+	record.Expected += expected
+}
+
+// round3 rounds a value to three decimals.
+func round3(v float64) float64 {
+	return math.Round(v*1000) / 1000
+}
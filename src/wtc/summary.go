@@ -0,0 +1,56 @@
+package main
+
+import (
+	"atomicfile"
+	"encoding/json"
+	"logger"
+	"progress"
+)
+
+// A Summary is the report of a run, archived next to its outputs.
+type Summary struct {
+	Command string `json:"command"`
+	Status  int    `json:"status"`
+	progress.Snapshot
+	Errors map[string]int `json:"errors"`
+}
+
+// writeSummary writes the summary of the run in the summary file. The run is
+// ending, so failures are only logged.
+func writeSummary(cmd *Command, code int) {
+	var summary = Summary{
+		Command:  cmd.Name,
+		Status:   code,
+		Snapshot: tracker.Snapshot(),
+		Errors:   run.Counts(),
+	}
+
+	file, err := atomicfile.Create(globals.Summary)
+	if err != nil {
+		log.Error("creating summary file", logger.M{
+			"path": globals.Summary,
+			"err":  err,
+		})
+		return
+	}
+
+	var enc = json.NewEncoder(file)
+	enc.SetIndent("", "\t")
+	err = enc.Encode(summary)
+	if err != nil {
+		file.Abort()
+		log.Error("writing summary file", logger.M{
+			"path": globals.Summary,
+			"err":  err,
+		})
+		return
+	}
+
+	err = file.Commit()
+	if err != nil {
+		log.Error("writing summary file", logger.M{
+			"path": globals.Summary,
+			"err":  err,
+		})
+	}
+}